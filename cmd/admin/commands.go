@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/auth"
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/shortener"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+	"github.com/Igorjr19/go-shorty/internal/transfer"
+)
+
+var errSomeMissing = errors.New("some codes do not exist")
+
+// actionResult reports what a command changed, or would change in a dry run.
+type actionResult struct {
+	Action string   `json:"action"`
+	DryRun bool     `json:"dry_run"`
+	Count  int64    `json:"count"`
+	Codes  []string `json:"codes,omitempty"`
+}
+
+func runSearch(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	query := fs.String("q", "", "Text to match in the code or destination")
+	owner := fs.String("owner", "", "Only links of this owner")
+	disabled := fs.Bool("disabled", false, "Only disabled links")
+	expired := fs.Bool("expired", false, "Only expired links")
+	limit := fs.Int("limit", 50, "Maximum number of links (0 = all)")
+	offset := fs.Int("offset", 0, "Number of links to skip")
+	fs.Parse(args)
+
+	opts := storage.ListOptions{
+		Limit:        *limit,
+		Offset:       *offset,
+		Query:        *query,
+		Owner:        *owner,
+		OnlyDisabled: *disabled,
+	}
+	if *expired {
+		now := time.Now()
+		opts.ExpiredBefore = &now
+	}
+
+	links, err := a.store.List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return writeJSON(links)
+	}
+	return printLinks(links)
+}
+
+func runDisable(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	now := time.Now()
+	return a.eachCode(ctx, "disable", requireCodes(fs), func(code string) error {
+		return a.store.SetDisabled(ctx, code, &now)
+	})
+}
+
+func runEnable(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	return a.eachCode(ctx, "enable", requireCodes(fs), func(code string) error {
+		return a.store.SetDisabled(ctx, code, nil)
+	})
+}
+
+func runDelete(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	return a.eachCode(ctx, "delete", requireCodes(fs), func(code string) error {
+		return a.store.Delete(ctx, code)
+	})
+}
+
+func runReassign(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	from := fs.String("from", "", "Move every link of this owner")
+	to := fs.String("to", "", "New owner (empty string clears it)")
+	fs.Parse(args)
+
+	if !isFlagSet(fs, "to") || (*from == "") == (fs.NArg() == 0) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *from == "" {
+		return a.eachCode(ctx, "reassign", fs.Args(), func(code string) error {
+			return a.store.SetOwner(ctx, code, *to)
+		})
+	}
+
+	links, err := a.store.List(ctx, storage.ListOptions{Owner: *from})
+	if err != nil {
+		return err
+	}
+
+	result := actionResult{Action: "reassign", DryRun: a.dryRun, Count: int64(len(links)), Codes: codes(links)}
+	if a.dryRun || len(links) == 0 {
+		return a.report(result)
+	}
+	if !a.confirm("Move %d links from %q to %q?", len(links), *from, *to) {
+		return errors.New("aborted")
+	}
+
+	result.Count, err = a.store.ReassignOwner(ctx, *from, *to)
+	if err != nil {
+		return err
+	}
+	return a.report(result)
+}
+
+func runPurgeExpired(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+
+	now := time.Now()
+	links, err := a.store.List(ctx, storage.ListOptions{ExpiredBefore: &now})
+	if err != nil {
+		return err
+	}
+
+	result := actionResult{Action: "purge", DryRun: a.dryRun, Count: int64(len(links)), Codes: codes(links)}
+	if a.dryRun || len(links) == 0 {
+		return a.report(result)
+	}
+	if !a.confirm("Delete %d expired links?", len(links)) {
+		return errors.New("aborted")
+	}
+
+	// Links expiring between the listing and now are purged too; the count
+	// reflects what was actually deleted.
+	result.Count, err = a.store.PurgeExpired(ctx, now)
+	if err != nil {
+		return err
+	}
+	return a.report(result)
+}
+
+func runImport(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	formatName := fs.String("format", "", "Input format: csv, ndjson, bitly or yourls (default from the file extension)")
+	onConflict := fs.String("on-conflict", "skip", "What to do with codes that already exist: skip, overwrite or fail")
+	fs.Parse(args)
+	path := requireFile(fs)
+
+	policy, err := transfer.ParseConflictPolicy(*onConflict)
+	if err != nil {
+		usageError("%v", err)
+	}
+	format := formatFor(*formatName, path, transfer.ParseImportFormat)
+
+	needsConfirmation := policy == transfer.Overwrite && !a.dryRun && !a.yes
+	if needsConfirmation && path == "-" {
+		usageError("Reading from stdin leaves no way to confirm; pass -yes to overwrite")
+	}
+
+	in, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if needsConfirmation && !a.confirm("Existing links with the same codes will be replaced. Continue?") {
+		return errors.New("aborted")
+	}
+
+	service := shortener.NewService(a.store, shortener.Options{Policy: a.policy})
+	report, err := transfer.Import(ctx, service, transfer.NewReader(in, format), transfer.ImportOptions{
+		OnConflict: policy,
+		DryRun:     a.dryRun,
+	})
+
+	if a.output == "json" {
+		if jsonErr := writeJSON(report); jsonErr != nil {
+			return jsonErr
+		}
+	} else {
+		printImportReport(report)
+	}
+	return err
+}
+
+func runExport(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	formatName := fs.String("format", "", "Output format: csv or ndjson (default from the -o extension)")
+	outPath := fs.String("o", "", "Write to this file instead of stdout")
+	query := fs.String("q", "", "Text to match in the code or destination")
+	owner := fs.String("owner", "", "Only links of this owner")
+	disabled := fs.Bool("disabled", false, "Only disabled links")
+	expired := fs.Bool("expired", false, "Only expired links")
+	createdAfter := fs.String("created-after", "", "Only links created at or after this RFC 3339 time")
+	createdBefore := fs.String("created-before", "", "Only links created before this RFC 3339 time")
+	fs.Parse(args)
+
+	opts := storage.ListOptions{Query: *query, Owner: *owner, OnlyDisabled: *disabled}
+	now := time.Now()
+	if *expired {
+		opts.ExpiredBefore = &now
+	}
+	opts.CreatedAfter = parseTimeFlag("created-after", *createdAfter)
+	opts.CreatedBefore = parseTimeFlag("created-before", *createdBefore)
+
+	out := os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	service := shortener.NewService(a.store, shortener.Options{})
+	n, err := transfer.Export(ctx, service, transfer.NewWriter(out, formatFor(*formatName, *outPath, transfer.ParseFormat)), opts)
+	if err != nil {
+		return err
+	}
+
+	if *outPath != "" {
+		if err := out.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d links to %s\n", n, *outPath)
+	}
+	return nil
+}
+
+func printImportReport(report transfer.Report) {
+	prefix := ""
+	if report.DryRun {
+		prefix = "Dry run: would have "
+	}
+	fmt.Printf("%simported %d, overwritten %d, skipped %d, invalid %d\n",
+		prefix, report.Imported, report.Overwritten, len(report.Skipped), len(report.Invalid))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, group := range []struct {
+		problem string
+		issues  []transfer.Issue
+	}{
+		{"skipped", report.Skipped},
+		{"invalid", report.Invalid},
+	} {
+		for _, issue := range group.issues {
+			fmt.Fprintf(w, "  line %d\t%s\t%s\t%s\n", issue.Line, group.problem, issue.Code, issue.Reason)
+		}
+	}
+	w.Flush()
+}
+
+func formatFor(name, path string, parse func(string) (transfer.Format, error)) transfer.Format {
+	if name == "" {
+		return transfer.FormatFromPath(path)
+	}
+	format, err := parse(name)
+	if err != nil {
+		usageError("%v", err)
+	}
+	return format
+}
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func requireFile(fs *flag.FlagSet) string {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs.Arg(0)
+}
+
+func parseTimeFlag(name, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		usageError("Invalid -%s: %s is not an RFC 3339 time", name, value)
+	}
+	return &t
+}
+
+func runKeys(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "create":
+		return createKey(ctx, a, fs, args[1:])
+	case "list":
+		return listKeys(ctx, a)
+	case "revoke":
+		return revokeKeys(ctx, a, fs, args[1:])
+	default:
+		usageError("Invalid keys command: %s. Use create, list or revoke", args[0])
+		return nil
+	}
+}
+
+func createKey(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	name := fs.String("name", "", "What the key is used for")
+	owner := fs.String("owner", "", "Owner recorded on links created with the key")
+	isAdmin := fs.Bool("admin", false, "Let the key manage every link and overwrite on import")
+	fs.Parse(args)
+
+	if *name == "" || *owner == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	key, token, err := auth.NewAPIKey(*name, *owner, time.Now())
+	if err != nil {
+		return err
+	}
+	key.Admin = *isAdmin
+
+	if a.dryRun {
+		kind := "key"
+		if key.Admin {
+			kind = "admin key"
+		}
+		fmt.Fprintf(os.Stderr, "Dry run: would create %s %q for %s\n", kind, key.Name, key.Owner)
+		return nil
+	}
+	if err := a.store.SaveAPIKey(ctx, key); err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return writeJSON(struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Owner string `json:"owner"`
+			Admin bool   `json:"admin"`
+			Key   string `json:"key"`
+		}{key.ID, key.Name, key.Owner, key.Admin, token})
+	}
+
+	fmt.Fprintf(os.Stderr, "Created key %s. It is shown only once:\n", key.ID)
+	fmt.Println(token)
+	return nil
+}
+
+func listKeys(ctx context.Context, a *admin) error {
+	keys, err := a.store.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		type keyJSON struct {
+			ID        string     `json:"id"`
+			Name      string     `json:"name"`
+			Owner     string     `json:"owner"`
+			Prefix    string     `json:"prefix"`
+			Admin     bool       `json:"admin"`
+			CreatedAt time.Time  `json:"created_at"`
+			RevokedAt *time.Time `json:"revoked_at,omitempty"`
+		}
+		out := make([]keyJSON, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, keyJSON{k.ID, k.Name, k.Owner, k.Prefix, k.Admin, k.CreatedAt, k.RevokedAt})
+		}
+		return writeJSON(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tOWNER\tPREFIX\tADMIN\tCREATED\tREVOKED")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s…\t%t\t%s\t%s\n", k.ID, k.Name, k.Owner, k.Prefix, k.Admin, formatTime(&k.CreatedAt), formatTime(k.RevokedAt))
+	}
+	return w.Flush()
+}
+
+func revokeKeys(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	ids := requireCodes(fs)
+
+	if a.dryRun {
+		return a.report(actionResult{Action: "revoke", DryRun: true, Count: int64(len(ids)), Codes: ids})
+	}
+	if !a.confirm("Revoke %d API keys? Clients using them will be rejected immediately.", len(ids)) {
+		return errors.New("aborted")
+	}
+
+	now := time.Now()
+	result := actionResult{Action: "revoke"}
+	var failed error
+	for _, id := range ids {
+		err := a.store.RevokeAPIKey(ctx, id, now)
+		if errors.Is(err, storage.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "%s: no active key with this ID\n", id)
+			failed = errors.New("some keys were not found or already revoked")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		result.Count++
+		result.Codes = append(result.Codes, id)
+	}
+
+	if err := a.report(result); err != nil {
+		return err
+	}
+	return failed
+}
+
+// eachCode applies fn to every existing code after showing them and asking
+// for confirmation. Unknown codes are reported and fail the command, but do
+// not stop the others from being processed.
+func (a *admin) eachCode(ctx context.Context, action string, codes []string, fn func(code string) error) error {
+	var (
+		links   []entity.Link
+		missing bool
+	)
+	for _, code := range codes {
+		link, err := a.store.Load(ctx, code)
+		if errors.Is(err, storage.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "%s: not found\n", code)
+			missing = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		links = append(links, link)
+	}
+
+	result := actionResult{Action: action, DryRun: a.dryRun}
+	if len(links) > 0 && !a.dryRun {
+		if a.output == "text" {
+			printLinks(links)
+		}
+		if !a.confirm("%s %d links?", capitalize(action), len(links)) {
+			return errors.New("aborted")
+		}
+	}
+
+	for _, link := range links {
+		if !a.dryRun {
+			if err := fn(link.Code); err != nil {
+				return fmt.Errorf("%s %s: %w", action, link.Code, err)
+			}
+		}
+		result.Count++
+		result.Codes = append(result.Codes, link.Code)
+	}
+
+	if err := a.report(result); err != nil {
+		return err
+	}
+	if missing {
+		return errSomeMissing
+	}
+	return nil
+}
+
+func (a *admin) report(result actionResult) error {
+	if a.output == "json" {
+		return writeJSON(result)
+	}
+
+	noun := "links"
+	if result.Action == "revoke" {
+		noun = "API keys"
+	}
+	if result.DryRun {
+		fmt.Printf("Dry run: would %s %d %s\n", result.Action, result.Count, noun)
+	} else {
+		fmt.Printf("%s %d %s\n", pastTense[result.Action], result.Count, noun)
+	}
+	for _, code := range result.Codes {
+		fmt.Printf("  %s\n", code)
+	}
+	return nil
+}
+
+func printLinks(links []entity.Link) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tOWNER\tCLICKS\tCREATED\tEXPIRES\tDISABLED\tDESTINATION")
+	for _, l := range links {
+		owner := l.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			l.Code, owner, strconv.FormatInt(l.Clicks, 10), formatTime(&l.CreatedAt),
+			formatTime(l.ExpiresAt), formatTime(l.DisabledAt), l.OriginalURL)
+	}
+	return w.Flush()
+}
+
+func codes(links []entity.Link) []string {
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		out = append(out, l.Code)
+	}
+	return out
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+var pastTense = map[string]string{
+	"disable":  "Disabled",
+	"enable":   "Enabled",
+	"delete":   "Deleted",
+	"reassign": "Reassigned",
+	"purge":    "Purged",
+	"revoke":   "Revoked",
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
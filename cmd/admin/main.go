@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/Igorjr19/go-shorty/internal/config"
+	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/shortener"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+	"github.com/joho/godotenv"
+)
+
+type store interface {
+	storage.AdminStorage
+	storage.APIKeyStorage
+}
+
+type admin struct {
+	store  store
+	policy shortener.Policy
+	dryRun bool
+	yes    bool
+	output string
+}
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, a *admin, fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"search", "search [-q <text>] [-owner <owner>] [-disabled] [-expired] [-limit <n>] [-offset <n>]", "Find links by code, destination, owner or state", runSearch},
+	{"disable", "disable <code>...", "Stop codes from redirecting without deleting them", runDisable},
+	{"enable", "enable <code>...", "Let disabled codes redirect again", runEnable},
+	{"delete", "delete <code>...", "Delete codes permanently", runDelete},
+	{"reassign", "reassign -to <owner> (-from <owner> | <code>...)", "Move links to another owner", runReassign},
+	{"purge-expired", "purge-expired", "Delete every expired link", runPurgeExpired},
+	{"import", "import [-format csv|ndjson|bitly|yourls] [-on-conflict skip|overwrite|fail] <file|->", "Restore links from an export of go-shorty, Bitly or YOURLS, keeping codes and timestamps", runImport},
+	{"export", "export [-format csv|ndjson] [-o <file>] [search filters]", "Stream links matching the filters as CSV or NDJSON", runExport},
+	{"keys", "keys create -name <name> -owner <owner> [-admin] | keys list | keys revoke <id>...", "Manage API keys", runKeys},
+}
+
+func main() {
+	envErr := godotenv.Load()
+
+	flag.Usage = usage
+	dryRun := flag.Bool("dry-run", false, "Show what would change without changing it")
+	yes := flag.Bool("yes", false, "Do not ask for confirmation before destructive actions")
+	output := flag.String("output", "text", "Output format: text or json")
+	loader := config.NewLoader(flag.CommandLine)
+	flag.Parse()
+
+	if *output != "text" && *output != "json" {
+		usageError("Invalid output format: %s. Use 'text' or 'json'", *output)
+	}
+
+	cfg, err := loader.Load()
+	if cfg != nil && loader.PrintConfig() {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", printErr)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if loader.PrintConfig() {
+		return
+	}
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd := findCommand(flag.Arg(0))
+	if cmd == nil {
+		usageError("Invalid command: %s. Run 'admin -h' for the list of commands", flag.Arg(0))
+	}
+
+	// Logs go to stderr so command output can be piped.
+	logger.InitWithWriter(cfg.Environment, os.Stderr)
+	if cfg.Log.Level != "" {
+		logger.SetLevel(cfg.Log.Level)
+	}
+	logger.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if envErr != nil {
+		logger.Debug(ctx, "No .env file found, using environment variables")
+	}
+
+	db, err := config.ConnectDB(ctx, cfg.Database)
+	if err != nil {
+		fatal(ctx, "Database unavailable", err)
+	}
+	defer db.Close()
+
+	a := &admin{
+		store: storage.NewPostgresStorage(db),
+		policy: shortener.Policy{
+			BlockedDomains:  cfg.Shortener.BlockedDomains,
+			ReservedAliases: cfg.Shortener.ReservedAliases,
+		},
+		dryRun: *dryRun,
+		yes:    *yes,
+		output: *output,
+	}
+
+	if err := cmd.run(ctx, a, newFlagSet(cmd), flag.Args()[1:]); err != nil {
+		db.Close()
+		fatal(ctx, "Admin command failed", err, slog.String("command", cmd.name))
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: admin [flags] %s\n\n%s\n", cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func requireCodes(fs *flag.FlagSet) []string {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs.Args()
+}
+
+// confirm asks before a destructive action. Anything but an explicit yes,
+// including a closed stdin, declines.
+func (a *admin) confirm(format string, args ...any) bool {
+	if a.yes {
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, format+" [y/N]: ", args...)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func fatal(ctx context.Context, msg string, err error, attrs ...any) {
+	logger.Error(ctx, msg, append(attrs, slog.String("error", err.Error()))...)
+	os.Exit(1)
+}
+
+func usageError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: admin [flags] <command> [command flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-15s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nDestructive commands ask for confirmation unless -yes is given.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func writeJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
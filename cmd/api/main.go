@@ -2,17 +2,24 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
-	"time"
 
 	"github.com/Igorjr19/go-shorty/internal/api"
 	"github.com/Igorjr19/go-shorty/internal/config"
+	"github.com/Igorjr19/go-shorty/internal/entity"
 	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/metadata"
+	"github.com/Igorjr19/go-shorty/internal/metrics"
 	"github.com/Igorjr19/go-shorty/internal/middleware"
+	"github.com/Igorjr19/go-shorty/internal/migrate"
 	"github.com/Igorjr19/go-shorty/internal/shortener"
 	"github.com/Igorjr19/go-shorty/internal/storage"
+	"github.com/Igorjr19/go-shorty/migrations"
 	"github.com/joho/godotenv"
 )
 
@@ -22,44 +29,127 @@ func main() {
 
 	}
 
-	env := getEnv("ENVIRONMENT", "development")
-	logger.Init(env)
+	loader := config.NewLoader(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if cfg != nil && loader.PrintConfig() {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", printErr)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if loader.PrintConfig() {
+		return
+	}
+
+	logger.Init(cfg.Environment)
+	if cfg.Log.Level != "" {
+		logger.SetLevel(cfg.Log.Level)
+	}
+	logger.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
 
 	ctx := logger.WithRequestID(context.Background(), "startup")
 	logger.Info(ctx, "Starting go-shorty server",
-		slog.String("environment", env),
+		slog.String("environment", cfg.Environment),
 		slog.String("version", "1.0.0"),
 	)
 
-	storage := storage.NewPostgresStorage(config.ConnectDB())
+	db, err := config.ConnectDB(ctx, cfg.Database)
+	if err != nil {
+		logger.Error(ctx, "Database unavailable", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if cfg.Migrations.OnStartup {
+		migrator := migrate.NewMigrator(db, migrationSource(cfg))
+		migrator.SetLockTimeout(cfg.Migrations.LockTimeout)
 
-	service := shortener.NewService(storage)
+		logger.Info(ctx, "Applying migrations before serving")
+		if err := migrator.Up(); err != nil {
+			logger.Error(ctx, "Startup migrations failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	storage := storage.NewPostgresStorage(db)
+
+	var onCreate func(entity.Link)
+	if cfg.Metadata.Enabled {
+		fetcher := metadata.NewFetcher(metadata.Options{
+			Timeout:      cfg.Metadata.Timeout,
+			MaxBytes:     int64(cfg.Metadata.MaxBytes),
+			MaxRedirects: cfg.Metadata.MaxRedirects,
+		})
+		refresher := metadata.NewRefresher(storage, fetcher, metadata.RefresherOptions{
+			Workers:  cfg.Metadata.Workers,
+			Interval: cfg.Metadata.RefreshInterval,
+			MaxAge:   cfg.Metadata.MaxAge,
+		})
+		go refresher.Run(logger.WithRequestID(context.Background(), "metadata"))
+		onCreate = refresher.Enqueue
+	}
+
+	service := shortener.NewService(storage, shortener.Options{
+		CodeLength:   cfg.Shortener.CodeLength,
+		WriteTimeout: cfg.Shortener.WriteTimeout,
+		ReadTimeout:  cfg.Shortener.ReadTimeout,
+		Policy:       shortenerPolicy(cfg),
+		OnCreate:     onCreate,
+	})
+
+	apiKeys := middleware.APIKeyAuth(storage, cfg.Auth.RequireAPIKey)
 
-	handler := api.NewHandler(service)
+	handler := api.NewHandler(service, api.Options{
+		BaseURL:      cfg.Server.BaseURL,
+		Auth:         apiKeys,
+		MaxBatchSize: cfg.Shortener.BatchMaxSize,
+		RedirectType: cfg.Shortener.RedirectType,
 
-	var readRateLimiter middleware.RateLimiter = middleware.NewInMemoryRateLimiter(10, time.Minute)
-	var writeRateLimiter middleware.RateLimiter = middleware.NewInMemoryRateLimiter(1000, time.Minute)
+		MaxImportBytes: int64(cfg.Shortener.ImportMaxBytes),
+	})
+
+	var readRateLimiter middleware.RateLimiter = middleware.NewInMemoryRateLimiter(cfg.RateLimit.Read.Requests, cfg.RateLimit.Read.Window)
+	var writeRateLimiter middleware.RateLimiter = middleware.NewInMemoryRateLimiter(cfg.RateLimit.Write.Requests, cfg.RateLimit.Write.Window)
+
+	go loader.Watch(ctx, cfg, func(next *config.Config) {
+		readRateLimiter.UpdateLimit(next.RateLimit.Read.Requests, next.RateLimit.Read.Window)
+		writeRateLimiter.UpdateLimit(next.RateLimit.Write.Requests, next.RateLimit.Write.Window)
+		logger.SetLevel(next.Log.Level)
+		service.SetPolicy(shortenerPolicy(next))
+	})
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /shorten", writeRateLimiter.Limit(handler.ShortenURL))
-	mux.HandleFunc("GET /{code}", readRateLimiter.Limit(handler.ResolveURL))
+	handler.Register(mux, readRateLimiter.Limit, writeRateLimiter.Limit)
+	mux.HandleFunc("GET /metrics", apiKeys(middleware.RequireAdmin(metrics.DatabaseHandler(db))))
 
 	finalHandler := middleware.RecoverMiddleware(
 		middleware.LoggingMiddleware(mux),
 	)
 
-	port := getEnv("PORT", "8080")
-	logger.Info(ctx, "Server started", slog.String("port", port))
+	logger.Info(ctx, "Server started", slog.String("port", cfg.Server.Port))
 
-	if err := http.ListenAndServe(":"+port, finalHandler); err != nil {
+	if err := http.ListenAndServe(":"+cfg.Server.Port, finalHandler); err != nil {
 		logger.Error(ctx, "Server failed to start", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func shortenerPolicy(cfg *config.Config) shortener.Policy {
+	return shortener.Policy{
+		BlockedDomains:  cfg.Shortener.BlockedDomains,
+		ReservedAliases: cfg.Shortener.ReservedAliases,
+	}
+}
+
+func migrationSource(cfg *config.Config) fs.FS {
+	if cfg.Migrations.Dir != "" {
+		return os.DirFS(cfg.Migrations.Dir)
 	}
-	return defaultValue
+	return migrations.FS
 }
@@ -1,30 +1,98 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
-	"log"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/Igorjr19/go-shorty/internal/config"
+	"github.com/Igorjr19/go-shorty/internal/logger"
 	"github.com/Igorjr19/go-shorty/internal/migrate"
+	"github.com/Igorjr19/go-shorty/migrations"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
-	}
+	envErr := godotenv.Load()
 
-	direction := flag.String("direction", "up", "Migration direction: up or down")
+	flag.Usage = usage
+	direction := flag.String("direction", "up", "Migration direction: up or down (used when no command is given)")
 	steps := flag.Int("steps", 0, "Number of migrations to run (0 = all)")
+	dryRun := flag.Bool("dry-run", false, "Print the SQL that would run without applying it")
+	force := flag.Bool("force", false, "Run even if applied migrations were modified on disk")
+	timestamp := flag.Bool("timestamp", false, "Version new migrations with a UTC timestamp instead of the next number")
+	output := flag.String("output", "text", "Output format for status, version and validate: text or json")
+	loader := config.NewLoader(flag.CommandLine)
 	flag.Parse()
 
-	db := config.ConnectDB()
+	command := *direction
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+
+	if *output != "text" && *output != "json" {
+		usageError("Invalid output format: %s. Use 'text' or 'json'", *output)
+	}
+
+	var targetVersion int
+	if command == "goto" || command == "force" {
+		targetVersion = parseVersionArg(command, flag.Args())
+	}
+
+	cfg, err := loader.Load()
+	if cfg != nil && loader.PrintConfig() {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", printErr)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if loader.PrintConfig() {
+		return
+	}
+
+	// Logs go to stderr so status and dry-run output can be piped.
+	logger.InitWithWriter(cfg.Environment, os.Stderr)
+	if cfg.Log.Level != "" {
+		logger.SetLevel(cfg.Log.Level)
+	}
+	logger.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
+
+	ctx := context.Background()
+	if envErr != nil {
+		logger.Debug(ctx, "No .env file found, using environment variables")
+	}
+
+	if command == "create" {
+		createMigration(ctx, cfg, flag.Args(), *timestamp)
+		return
+	}
+
+	db, err := config.ConnectDB(ctx, cfg.Database)
+	if err != nil {
+		fatal(ctx, "Database unavailable", err)
+	}
 	defer db.Close()
 
-	migrator := migrate.NewMigrator(db, "migrations")
+	migrator := migrate.NewMigrator(db, migrationSource(cfg))
+	migrator.SetDryRun(*dryRun)
+	migrator.SetForce(*force)
+	migrator.SetLockTimeout(cfg.Migrations.LockTimeout)
 
-	var err error
-	switch *direction {
+	start := time.Now()
+
+	switch command {
 	case "up":
 		if *steps > 0 {
 			err = migrator.UpSteps(*steps)
@@ -37,13 +105,188 @@ func main() {
 		} else {
 			err = migrator.Down()
 		}
+	case "status":
+		err = printStatus(migrator, *output)
+	case "version":
+		err = printVersion(migrator, *output)
+	case "validate":
+		err = validate(migrator, *output)
+	case "goto":
+		err = migrator.Goto(targetVersion)
+	case "force":
+		err = migrator.Force(targetVersion)
+	case "redo":
+		err = migrator.Redo()
 	default:
-		log.Fatalf("Invalid direction: %s. Use 'up' or 'down'", *direction)
+		usageError("Invalid command: %s. Run 'migrate -h' for the list of commands", command)
+	}
+
+	if err != nil {
+		db.Close()
+		fatal(ctx, "Migration command failed", err, slog.String("command", command))
+	}
+
+	if command != "status" && command != "version" && command != "validate" && !*dryRun {
+		logger.Info(ctx, "Migration command completed",
+			slog.String("command", command),
+			slog.Duration("duration", time.Since(start)),
+		)
+	}
+}
+
+func fatal(ctx context.Context, msg string, err error, attrs ...any) {
+	logger.Error(ctx, msg, append(attrs, slog.String("error", err.Error()))...)
+	os.Exit(1)
+}
+
+func usageError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: migrate [flags] [command] [command flags]
+
+Commands:
+  up              Apply pending migrations
+  down            Revert applied migrations
+  status          List applied, pending and missing migrations
+  version         Print the current schema version
+  validate        Report drifted, missing and out-of-order migrations (for CI)
+  create <name>   Write the next up/down pair to the migrations directory
+  goto <version>  Apply or revert until exactly <version> is current
+  redo            Revert and re-apply the latest migration
+  force <version> Record exactly the migrations up to <version> as applied
+                  and clean without running them, after a failed run
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func createMigration(ctx context.Context, cfg *config.Config, args []string, timestamp bool) {
+	if len(args) != 1 {
+		usageError("Usage: migrate create [-timestamp] <name>")
 	}
 
+	dir := cfg.Migrations.Dir
+	if dir == "" {
+		dir = "migrations"
+	}
+
+	upPath, downPath, err := migrate.Create(dir, args[0], timestamp, time.Now())
 	if err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		fatal(ctx, "Failed to create migration", err)
 	}
 
-	log.Println("Migration completed successfully!")
+	fmt.Println(upPath)
+	fmt.Println(downPath)
+}
+
+func parseVersionArg(command string, args []string) int {
+	if len(args) != 1 {
+		usageError("Usage: migrate %s <version>", command)
+	}
+
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 0 {
+		usageError("Invalid version: %s", args[0])
+	}
+	return version
+}
+
+func printStatus(migrator *migrate.Migrator, output string) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		return writeJSON(statuses)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	return w.Flush()
+}
+
+func printVersion(migrator *migrate.Migrator, output string) error {
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		return writeJSON(map[string]int{"version": version})
+	}
+
+	fmt.Println(version)
+	return nil
+}
+
+func validate(migrator *migrate.Migrator, output string) error {
+	report, err := migrator.Validate()
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		err := writeJSON(struct {
+			OK bool `json:"ok"`
+			migrate.ValidationReport
+		}{report.OK(), report})
+		if err != nil || report.OK() {
+			return err
+		}
+		return validationError(report)
+	}
+
+	if report.OK() {
+		fmt.Println("All migrations are valid")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tPROBLEM\tDETAIL")
+	for _, group := range []struct {
+		problem string
+		issues  []migrate.MigrationIssue
+	}{
+		{"dirty", report.Dirty},
+		{"drifted", report.Drifted},
+		{"missing", report.Missing},
+		{"out-of-order", report.OutOfOrder},
+	} {
+		for _, issue := range group.issues {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", issue.Version, issue.Name, group.problem, issue.Detail)
+		}
+	}
+	w.Flush()
+
+	return validationError(report)
+}
+
+func validationError(report migrate.ValidationReport) error {
+	return fmt.Errorf("%d dirty, %d drifted, %d missing, %d out-of-order migrations",
+		len(report.Dirty), len(report.Drifted), len(report.Missing), len(report.OutOfOrder))
+}
+
+func writeJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func migrationSource(cfg *config.Config) fs.FS {
+	if cfg.Migrations.Dir != "" {
+		return os.DirFS(cfg.Migrations.Dir)
+	}
+	return migrations.FS
 }
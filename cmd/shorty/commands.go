@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Igorjr19/go-shorty/pkg/client"
+)
+
+func runShorten(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("shorten")
+	alias := fs.String("alias", "", "Custom code instead of a generated one")
+	redirectType := fs.Int("redirect", 0, "Redirect status: 301, 302, 307 or 308 (default from the server)")
+	forwardPath := fs.Bool("forward-path", false, "Append the path visited after the code to the URL")
+	forwardQuery := fs.String("forward-query", "", "Merge the visited query into the URL's: prefer_request or prefer_link")
+	fs.Parse(args)
+	requireArgs(fs, 1)
+
+	link, err := a.client.Shorten(ctx, client.ShortenRequest{
+		URL:          fs.Arg(0),
+		Alias:        *alias,
+		RedirectType: *redirectType,
+		ForwardPath:  *forwardPath,
+		ForwardQuery: *forwardQuery,
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return writeJSON(link)
+	}
+	fmt.Println(link.ShortURL)
+	return nil
+}
+
+func runInfo(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("info")
+	fs.Parse(args)
+	requireArgs(fs, 1)
+
+	link, err := a.client.Get(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return writeJSON(link)
+	}
+	return writeFields([][2]string{
+		{"Code", link.Code},
+		{"Short URL", link.ShortURL},
+		{"Destination", link.OriginalURL},
+		{"Created", formatTime(&link.CreatedAt)},
+		{"Clicks", strconv.FormatInt(link.Clicks, 10)},
+		{"Last click", formatTime(link.LastClickedAt)},
+	})
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("list")
+	limit := fs.Int("limit", 0, "Maximum number of links (0 = server default)")
+	offset := fs.Int("offset", 0, "Number of links to skip")
+	fs.Parse(args)
+	requireArgs(fs, 0)
+
+	links, err := a.client.List(ctx, client.ListOptions{Limit: *limit, Offset: *offset})
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return writeJSON(links)
+	}
+
+	rows := make([][]string, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []string{
+			link.Code,
+			strconv.FormatInt(link.Clicks, 10),
+			formatTime(&link.CreatedAt),
+			link.OriginalURL,
+		})
+	}
+	return writeTable([]string{"CODE", "CLICKS", "CREATED", "DESTINATION"}, rows)
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("delete")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	type result struct {
+		Code  string `json:"code"`
+		Error string `json:"error,omitempty"`
+	}
+
+	var results []result
+	failed := false
+	for _, code := range fs.Args() {
+		r := result{Code: code}
+		if err := a.client.Delete(ctx, code); err != nil {
+			r.Error = err.Error()
+			failed = true
+		}
+		results = append(results, r)
+	}
+
+	if a.output == "json" {
+		if err := writeJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.Code, r.Error)
+			} else {
+				fmt.Printf("Deleted %s\n", r.Code)
+			}
+		}
+	}
+
+	if failed {
+		return errSomeFailed
+	}
+	return nil
+}
+
+func runStats(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("stats")
+	fs.Parse(args)
+	requireArgs(fs, 1)
+
+	stats, err := a.client.Stats(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return writeJSON(stats)
+	}
+	return writeFields([][2]string{
+		{"Code", stats.Code},
+		{"Clicks", strconv.FormatInt(stats.Clicks, 10)},
+		{"Last click", formatTime(stats.LastClickedAt)},
+		{"Created", formatTime(&stats.CreatedAt)},
+	})
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("import")
+	stopOnError := fs.Bool("stop-on-error", false, "Stop after the first batch with a URL that cannot be shortened")
+	batchSize := fs.Int("batch-size", 100, "URLs sent per request, up to the server's batch limit")
+	fs.Parse(args)
+	requireArgs(fs, 1)
+
+	if *batchSize < 1 {
+		usageError("Invalid batch size: %d", *batchSize)
+	}
+
+	var in io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	type result struct {
+		Line     int    `json:"line"`
+		URL      string `json:"url"`
+		ShortURL string `json:"short_url,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+
+	var (
+		results []result
+		lines   []int
+		batch   []client.ShortenRequest
+		failed  bool
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		batchResults, err := a.client.ShortenBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		for i, br := range batchResults {
+			res := result{Line: lines[i], URL: batch[i].URL}
+			switch {
+			case br.Err != nil:
+				res.Error = br.Err.Error()
+				failed = true
+			case br.Link == nil:
+				res.Error = "no result returned by the server"
+				failed = true
+			default:
+				res.ShortURL = br.Link.ShortURL
+			}
+			results = append(results, res)
+
+			if a.output != "json" {
+				if res.Error != "" {
+					fmt.Fprintf(os.Stderr, "line %d: %s: %s\n", res.Line, res.URL, res.Error)
+				} else {
+					fmt.Printf("%s\t%s\n", res.ShortURL, res.URL)
+				}
+			}
+		}
+
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	r := csv.NewReader(in)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	for !(failed && *stopOnError) {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := r.FieldPos(0)
+		req := client.ShortenRequest{URL: strings.TrimSpace(record[0])}
+		if len(record) > 1 {
+			req.Alias = strings.TrimSpace(record[1])
+		}
+		if req.URL == "" || (line == 1 && strings.EqualFold(req.URL, "url")) {
+			continue
+		}
+
+		batch = append(batch, req)
+		lines = append(lines, line)
+		if len(batch) == *batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !(failed && *stopOnError) {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if a.output == "json" {
+		if err := writeJSON(results); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return errSomeFailed
+	}
+	return nil
+}
+
+func runCompletion(_ context.Context, _ *app, args []string) error {
+	fs := newFlagSet("completion")
+	fs.Parse(args)
+	requireArgs(fs, 1)
+
+	script, ok := completionScripts[fs.Arg(0)]
+	if !ok {
+		usageError("Unsupported shell: %s. Use bash, zsh or fish", fs.Arg(0))
+	}
+
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	_, err := fmt.Printf(script, strings.Join(names, " "))
+	return err
+}
@@ -0,0 +1,37 @@
+package main
+
+// Each script receives the space-separated command names through %[1]s.
+var completionScripts = map[string]string{
+	"bash": `# shorty bash completion, load with: source <(shorty completion bash)
+_shorty() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=($(compgen -W "%[1]s" -- "$cur"))
+	elif [ "${COMP_WORDS[1]}" = "completion" ]; then
+		COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+	elif [ "${COMP_WORDS[1]}" = "import" ]; then
+		COMPREPLY=($(compgen -f -- "$cur"))
+	fi
+}
+complete -F _shorty shorty
+`,
+	"zsh": `#compdef shorty
+# shorty zsh completion, load with: source <(shorty completion zsh)
+_shorty() {
+	if (( CURRENT == 2 )); then
+		compadd %[1]s
+	elif [[ ${words[2]} == completion ]]; then
+		compadd bash zsh fish
+	elif [[ ${words[2]} == import ]]; then
+		_files
+	fi
+}
+compdef _shorty shorty
+`,
+	"fish": `# shorty fish completion, load with: shorty completion fish | source
+complete -c shorty -f
+complete -c shorty -n __fish_use_subcommand -a "%[1]s"
+complete -c shorty -n "__fish_seen_subcommand_from completion" -a "bash zsh fish"
+complete -c shorty -n "__fish_seen_subcommand_from import" -F
+`,
+}
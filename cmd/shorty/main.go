@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/Igorjr19/go-shorty/pkg/client"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, app *app, args []string) error
+}
+
+var commands []command
+
+// commands is filled in init because the completion command lists it.
+func init() {
+	commands = []command{
+		{"shorten", "shorten [-alias <alias>] [-redirect <status>] [-forward-path] [-forward-query <mode>] <url>", "Shorten a URL", runShorten},
+		{"info", "info <code>", "Show a link", runInfo},
+		{"list", "list [-limit <n>] [-offset <n>]", "List links, newest first", runList},
+		{"delete", "delete <code>...", "Delete links", runDelete},
+		{"stats", "stats <code>", "Show click statistics for a link", runStats},
+		{"import", "import [-batch-size <n>] [-stop-on-error] <file|->", "Shorten every URL in a file with one 'url[,alias]' per line", runImport},
+		{"completion", "completion <bash|zsh|fish>", "Print a shell completion script", runCompletion},
+	}
+}
+
+type app struct {
+	client *client.Client
+	output string
+}
+
+func main() {
+	flag.Usage = usage
+	configPath := flag.String("config", "", "Config file (default $SHORTY_CONFIG or <user config dir>/shorty/config.yaml)")
+	server := flag.String("server", "", "Server URL (overrides SHORTY_SERVER)")
+	apiKey := flag.String("api-key", "", "API key (overrides SHORTY_API_KEY)")
+	output := flag.String("output", "", "Output format: table or json (overrides SHORTY_OUTPUT)")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd := findCommand(flag.Arg(0))
+	if cmd == nil {
+		usageError("Unknown command: %s. Run 'shorty -h' for the list of commands", flag.Arg(0))
+	}
+
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	settings.override(*server, *apiKey, *output)
+
+	if settings.Output != "table" && settings.Output != "json" {
+		usageError("Invalid output format: %s. Use 'table' or 'json'", settings.Output)
+	}
+
+	a := &app{output: settings.Output}
+	if cmd.name != "completion" {
+		a.client, err = client.New(settings.Server, client.WithAPIKey(settings.APIKey), client.WithUserAgent("shorty-cli"))
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd.run(ctx, a, flag.Args()[1:]); err != nil {
+		stop()
+		fatal(err)
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// newFlagSet parses the flags of a single command and prints its usage line
+// on -h or a bad flag.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		cmd := findCommand(name)
+		fmt.Fprintf(fs.Output(), "Usage: shorty %s\n\n%s\n", cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func requireArgs(fs *flag.FlagSet, n int) {
+	if fs.NArg() != n {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+var errSomeFailed = errors.New("some links could not be processed")
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "shorty: %v\n", err)
+	os.Exit(1)
+}
+
+func usageError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: shorty [flags] <command> [command flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-36s %s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintf(out, `
+Settings are read from the config file, then SHORTY_SERVER, SHORTY_API_KEY
+and SHORTY_OUTPUT, then flags.
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost:8080"
+
+type settings struct {
+	Server string `yaml:"server"`
+	APIKey string `yaml:"api_key"`
+	Output string `yaml:"output"`
+}
+
+// loadSettings reads the config file, if any, and applies the environment on
+// top. An explicitly named file must exist; the default one is optional.
+func loadSettings(path string) (*settings, error) {
+	s := &settings{
+		Server: defaultServer,
+		Output: "table",
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("SHORTY_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "shorty", "config.yaml")
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := yaml.Unmarshal(data, s); err != nil {
+				return nil, fmt.Errorf("parse %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	s.override(os.Getenv("SHORTY_SERVER"), os.Getenv("SHORTY_API_KEY"), os.Getenv("SHORTY_OUTPUT"))
+	return s, nil
+}
+
+func (s *settings) override(server, apiKey, output string) {
+	if server != "" {
+		s.Server = server
+	}
+	if apiKey != "" {
+		s.APIKey = apiKey
+	}
+	if output != "" {
+		s.Output = output
+	}
+}
@@ -3,8 +3,11 @@ module github.com/Igorjr19/go-shorty
 go 1.25.6
 
 require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.11.0
-	github.com/google/uuid v1.6.0
+	golang.org/x/net v0.57.0
+	gopkg.in/yaml.v3 v3.0.1
+	rsc.io/qr v0.2.0
 )
-
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+// redirectTarget is where a visit of link ends up: its destination, followed
+// by the rest of the visited path and merged with the visited query when the
+// link forwards them.
+func redirectTarget(link entity.Link, rest, query string) (string, error) {
+	if (rest == "" || !link.ForwardPath) && (query == "" || link.ForwardQuery == entity.QueryDropped) {
+		return link.OriginalURL, nil
+	}
+
+	u, err := url.Parse(link.OriginalURL)
+	if err != nil {
+		return "", err
+	}
+
+	if rest != "" && link.ForwardPath {
+		u = u.JoinPath(rest)
+	}
+	if query != "" && link.ForwardQuery != entity.QueryDropped {
+		u.RawQuery = mergeQuery(u.RawQuery, query, link.ForwardQuery == entity.QueryPreferRequest)
+	}
+	return u.String(), nil
+}
+
+// mergeQuery appends the parameters of visit to those of dest, leaving both
+// in their original order and encoding. A key found in both keeps all the
+// values of one side: the visit's when visitWins, dest's otherwise.
+func mergeQuery(dest, visit string, visitWins bool) string {
+	destParams, visitParams := splitQuery(dest), splitQuery(visit)
+	destKeys, visitKeys := queryKeys(destParams), queryKeys(visitParams)
+
+	merged := make([]string, 0, len(destParams)+len(visitParams))
+	for _, param := range destParams {
+		if !visitWins || !visitKeys[queryKey(param)] {
+			merged = append(merged, param)
+		}
+	}
+	for _, param := range visitParams {
+		if visitWins || !destKeys[queryKey(param)] {
+			merged = append(merged, param)
+		}
+	}
+	return strings.Join(merged, "&")
+}
+
+func splitQuery(query string) []string {
+	var params []string
+	for _, param := range strings.Split(query, "&") {
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+func queryKeys(params []string) map[string]bool {
+	keys := make(map[string]bool, len(params))
+	for _, param := range params {
+		keys[queryKey(param)] = true
+	}
+	return keys
+}
+
+func queryKey(param string) string {
+	key, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
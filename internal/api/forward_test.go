@@ -0,0 +1,118 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestMergeQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		dest      string
+		visit     string
+		visitWins bool
+		want      string
+	}{
+		{"no visit query", "a=1", "", true, "a=1"},
+		{"no destination query", "", "a=1", false, "a=1"},
+		{"distinct keys keep their order", "a=1&b=2", "c=3&d=4", false, "a=1&b=2&c=3&d=4"},
+		{"visit wins", "a=1&b=2", "b=3&c=4", true, "a=1&b=3&c=4"},
+		{"destination wins", "a=1&b=2", "b=3&c=4", false, "a=1&b=2&c=4"},
+		{"repeated keys move together", "tag=x&tag=y&a=1", "tag=z", true, "a=1&tag=z"},
+		{"repeated visit keys are dropped together", "tag=x", "tag=y&tag=z&a=1", false, "tag=x&a=1"},
+		{"escaped keys match", "utm%5Fsource=dest", "utm_source=visit", true, "utm_source=visit"},
+		{"encoding is kept", "q=a%20b", "r=c+d&s=%2F", false, "q=a%20b&r=c+d&s=%2F"},
+		{"keys without values", "flag", "flag=1&other", false, "flag&other"},
+		{"empty params are dropped", "a=1&&", "&b=2", false, "a=1&b=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeQuery(tt.dest, tt.visit, tt.visitWins); got != tt.want {
+				t.Errorf("mergeQuery(%q, %q, %v) = %q, want %q", tt.dest, tt.visit, tt.visitWins, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectTarget(t *testing.T) {
+	const dest = "https://example.com/base?ref=link"
+
+	tests := []struct {
+		name  string
+		link  entity.Link
+		rest  string
+		query string
+		want  string
+	}{
+		{
+			name:  "plain link ignores the visit",
+			link:  entity.Link{OriginalURL: dest},
+			rest:  "docs",
+			query: "ref=visit",
+			want:  dest,
+		},
+		{
+			name: "destination is kept verbatim when nothing is forwarded",
+			link: entity.Link{OriginalURL: "https://example.com/a%2Fb?x=1&y", ForwardPath: true, ForwardQuery: entity.QueryPreferRequest},
+			want: "https://example.com/a%2Fb?x=1&y",
+		},
+		{
+			name: "path is appended",
+			link: entity.Link{OriginalURL: dest, ForwardPath: true},
+			rest: "docs/page",
+			want: "https://example.com/base/docs/page?ref=link",
+		},
+		{
+			name: "path is appended to a trailing slash",
+			link: entity.Link{OriginalURL: "https://example.com/base/", ForwardPath: true},
+			rest: "docs",
+			want: "https://example.com/base/docs",
+		},
+		{
+			name: "path cannot climb above the destination",
+			link: entity.Link{OriginalURL: dest, ForwardPath: true},
+			rest: "../../etc",
+			want: "https://example.com/etc?ref=link",
+		},
+		{
+			name:  "query without path forwarding",
+			link:  entity.Link{OriginalURL: dest, ForwardQuery: entity.QueryPreferLink},
+			rest:  "docs",
+			query: "ref=visit&utm=1",
+			want:  "https://example.com/base?ref=link&utm=1",
+		},
+		{
+			name:  "query preferring the request",
+			link:  entity.Link{OriginalURL: dest, ForwardQuery: entity.QueryPreferRequest},
+			query: "ref=visit",
+			want:  "https://example.com/base?ref=visit",
+		},
+		{
+			name:  "path and query",
+			link:  entity.Link{OriginalURL: "https://example.com", ForwardPath: true, ForwardQuery: entity.QueryPreferRequest},
+			rest:  "a b",
+			query: "q=1",
+			want:  "https://example.com/a%20b?q=1",
+		},
+		{
+			name:  "dropped query",
+			link:  entity.Link{OriginalURL: dest, ForwardPath: true},
+			query: "ref=visit",
+			want:  dest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := redirectTarget(tt.link, tt.rest, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("redirectTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,29 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/Igorjr19/go-shorty/internal/auth"
+	"github.com/Igorjr19/go-shorty/internal/entity"
 	"github.com/Igorjr19/go-shorty/internal/logger"
 	"github.com/Igorjr19/go-shorty/internal/shortener"
+	"github.com/Igorjr19/go-shorty/internal/storage"
 )
 
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL          string                 `json:"url"`
+	Alias        string                 `json:"alias,omitempty"`
+	ExpiresAt    *time.Time             `json:"expires_at,omitempty"`
+	RedirectType int                    `json:"redirect_type,omitempty"`
+	ForwardPath  bool                   `json:"forward_path,omitempty"`
+	ForwardQuery entity.QueryForwarding `json:"forward_query,omitempty"`
 }
 
 type ShortenResponse struct {
@@ -20,11 +32,42 @@ type ShortenResponse struct {
 
 type Handler struct {
 	service *shortener.Service
+	opts    Options
 }
 
-func NewHandler(service *shortener.Service) *Handler {
+type Options struct {
+	// BaseURL is the public address short links are served from. When empty
+	// it is derived from each request.
+	BaseURL string
+	// PathPrefix is where the routes are mounted when the handler is served
+	// below the root of another mux. It is only used without a BaseURL.
+	PathPrefix string
+	// Auth wraps every route except redirects, e.g. to check API keys.
+	// Without it only redirects and shortening are served.
+	Auth Middleware
+	// MaxBatchSize caps the items of a batch request, 100 when zero.
+	MaxBatchSize int
+	// MaxImportBytes caps the body of an import request, 32 MiB when zero.
+	MaxImportBytes int64
+	// RedirectType is the status of links without one of their own, 302
+	// unless set to 301, 307 or 308.
+	RedirectType int
+}
+
+func NewHandler(service *shortener.Service, opts Options) *Handler {
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = defaultMaxBatchSize
+	}
+	if opts.MaxImportBytes <= 0 {
+		opts.MaxImportBytes = defaultMaxImportBytes
+	}
+	if opts.RedirectType == 0 || !entity.ValidRedirectType(opts.RedirectType) {
+		opts.RedirectType = http.StatusFound
+	}
+
 	return &Handler{
 		service: service,
+		opts:    opts,
 	}
 }
 
@@ -49,35 +92,43 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug(r.Context(), "Creating short URL", slog.String("original_url", req.URL))
 
-	code, err := h.service.Shorten(req.URL)
+	link, err := h.service.Shorten(r.Context(), req.serviceRequest(r))
+	if isValidationError(err) {
+		logger.Warn(r.Context(), "Short URL rejected",
+			slog.String("original_url", req.URL),
+			slog.String("alias", req.Alias),
+			slog.String("error", err.Error()),
+		)
+		writeServiceError(w, err)
+		return
+	}
 	if err != nil {
 		logger.Error(r.Context(), "Failed to create short URL",
 			slog.String("original_url", req.URL),
 			slog.String("error", err.Error()),
 		)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
 	logger.Info(r.Context(), "Short URL created successfully",
-		slog.String("code", code),
+		slog.String("code", link.Code),
 		slog.String("original_url", req.URL),
 	)
 
-	fullURL := fmt.Sprintf("http://%s/%s\n", r.Host, code)
-
 	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"qrcode\"", h.qrURL(r, link.Code)))
 	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte(fullURL))
+	w.Write([]byte(h.shortURL(r, link.Code) + "\n"))
 }
 
+// ResolveURL redirects to the destination of a link. GET and HEAD are always
+// redirected; other methods only by links whose redirect type keeps the
+// method, 307 and 308. A path after the code is only accepted by links that
+// forward it.
 func (h *Handler) ResolveURL(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	code := r.PathValue("code")
+	rest := r.PathValue("rest")
 	if code == "" {
 		code = r.URL.Path[1:]
 	}
@@ -88,19 +139,163 @@ func (h *Handler) ResolveURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if preview, ok := strings.CutSuffix(code, "+"); ok && rest == "" && r.Method == http.MethodGet {
+		h.preview(w, r, preview)
+		return
+	}
+
 	logger.Debug(r.Context(), "Resolving short URL", slog.String("code", code))
 
-	url, err := h.service.Resolve(code)
-	if err != nil {
+	if rest != "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		// Checked first so a refused request is not counted as a click.
+		link, err := h.service.Preview(r.Context(), code)
+		if err == nil && rest != "" && !link.ForwardPath {
+			http.NotFound(w, r)
+			return
+		}
+		if err == nil && r.Method != http.MethodGet && r.Method != http.MethodHead && !keepsMethod(h.redirectType(link)) {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+	}
+
+	link, err := h.service.Follow(r.Context(), code)
+	if errors.Is(err, storage.ErrNotFound) {
 		logger.Warn(r.Context(), "Short URL not found", slog.String("code", code))
 		http.NotFound(w, r)
 		return
 	}
+	if errors.Is(err, shortener.ErrLinkDisabled) || errors.Is(err, shortener.ErrLinkExpired) {
+		logger.Warn(r.Context(), "Short URL is no longer available",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		writeServiceError(w, err)
+		return
+	}
+	if err != nil {
+		logger.Error(r.Context(), "Failed to resolve short URL",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		writeServiceError(w, err)
+		return
+	}
+
+	target, err := redirectTarget(link, rest, r.URL.RawQuery)
+	if err != nil {
+		logger.Error(r.Context(), "Failed to build redirect target",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		writeServiceError(w, err)
+		return
+	}
 
 	logger.Info(r.Context(), "Short URL resolved successfully",
 		slog.String("code", code),
-		slog.String("original_url", url),
+		slog.String("original_url", target),
 	)
 
-	http.Redirect(w, r, url, http.StatusFound)
+	status := h.redirectType(link)
+	w.Header().Set("Cache-Control", cacheControl(status, link.ExpiresAt, time.Now()))
+	http.Redirect(w, r, target, status)
+}
+
+func (h *Handler) redirectType(link entity.Link) int {
+	if link.RedirectType != 0 {
+		return link.RedirectType
+	}
+	return h.opts.RedirectType
+}
+
+func keepsMethod(status int) bool {
+	return status == http.StatusTemporaryRedirect || status == http.StatusPermanentRedirect
+}
+
+// cacheControl lets browsers and proxies keep permanent redirects for a day,
+// or until the link expires if that is sooner, so they are not remembered
+// forever. Temporary redirects are not cached, so every visit is counted.
+func cacheControl(status int, expiresAt *time.Time, now time.Time) string {
+	if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+		return "private, no-cache"
+	}
+
+	maxAge := permanentRedirectMaxAge
+	if expiresAt != nil {
+		maxAge = min(maxAge, expiresAt.Sub(now))
+	}
+	if maxAge <= 0 {
+		return "private, no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+func isValidationError(err error) bool {
+	return errors.Is(err, shortener.ErrInvalidURL) ||
+		errors.Is(err, shortener.ErrBlockedDomain) ||
+		errors.Is(err, shortener.ErrInvalidAlias) ||
+		errors.Is(err, shortener.ErrReservedAlias) ||
+		errors.Is(err, shortener.ErrAliasTaken) ||
+		errors.Is(err, shortener.ErrRejected) ||
+		errors.Is(err, shortener.ErrInvalidExpiry) ||
+		errors.Is(err, shortener.ErrInvalidRedirectType) ||
+		errors.Is(err, shortener.ErrInvalidQueryForwarding)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	// The client is gone; nobody is left to read the response.
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	status, message := errorStatus(err)
+	http.Error(w, message, status)
+}
+
+func errorStatus(err error) (int, string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit)
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound, "Link not found"
+	case errors.Is(err, shortener.ErrAliasTaken):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, shortener.ErrLinkDisabled), errors.Is(err, shortener.ErrLinkExpired):
+		return http.StatusGone, err.Error()
+	case isValidationError(err):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Request timed out"
+	case errors.Is(err, errors.ErrUnsupported):
+		return http.StatusNotImplemented, "Not supported by this storage"
+	default:
+		return http.StatusInternalServerError, "Internal server error"
+	}
+}
+
+func (req ShortenRequest) serviceRequest(r *http.Request) shortener.Request {
+	return shortener.Request{
+		URL:          req.URL,
+		Alias:        req.Alias,
+		Owner:        auth.Owner(r.Context()),
+		ExpiresAt:    req.ExpiresAt,
+		RedirectType: req.RedirectType,
+		ForwardPath:  req.ForwardPath,
+		ForwardQuery: req.ForwardQuery,
+	}
+}
+
+func (h *Handler) shortURL(r *http.Request, code string) string {
+	if h.opts.BaseURL != "" {
+		return strings.TrimRight(h.opts.BaseURL, "/") + "/" + code
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/%s", scheme, r.Host, h.opts.PathPrefix, code)
 }
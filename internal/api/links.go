@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/auth"
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/shortener"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+const (
+	defaultListLimit    = 50
+	maxListLimit        = 1000
+	defaultMaxBatchSize = 100
+	// maxBatchItemBytes is the body allowed per batch item, room for a long
+	// URL and every option.
+	maxBatchItemBytes = 16 << 10
+
+	defaultMaxImportBytes = 32 << 20
+
+	permanentRedirectMaxAge = 24 * time.Hour
+)
+
+type LinkResponse struct {
+	Code          string                 `json:"code"`
+	ShortURL      string                 `json:"short_url"`
+	QRURL         string                 `json:"qr_url"`
+	OriginalURL   string                 `json:"original_url"`
+	Title         string                 `json:"title,omitempty"`
+	Description   string                 `json:"description,omitempty"`
+	FaviconURL    string                 `json:"favicon_url,omitempty"`
+	ImageURL      string                 `json:"image_url,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Clicks        int64                  `json:"clicks"`
+	LastClickedAt *time.Time             `json:"last_clicked_at,omitempty"`
+	Owner         string                 `json:"owner,omitempty"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty"`
+	DisabledAt    *time.Time             `json:"disabled_at,omitempty"`
+	RedirectType  int                    `json:"redirect_type"`
+	ForwardPath   bool                   `json:"forward_path,omitempty"`
+	ForwardQuery  entity.QueryForwarding `json:"forward_query,omitempty"`
+}
+
+type ListResponse struct {
+	Links  []LinkResponse `json:"links"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+type StatsResponse struct {
+	Code          string     `json:"code"`
+	Clicks        int64      `json:"clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type BatchResult struct {
+	Index  int           `json:"index"`
+	Status int           `json:"status"`
+	Link   *LinkResponse `json:"link,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	var req ShortenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Warn(r.Context(), "Invalid request body", slog.String("error", err.Error()))
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.URL == "" {
+		writeJSONError(w, http.StatusBadRequest, "URL is required")
+		return
+	}
+
+	link, err := h.service.Shorten(r.Context(), req.serviceRequest(r))
+	if err != nil {
+		h.writeAPIError(w, r, "Failed to create short URL", err)
+		return
+	}
+
+	logger.Info(r.Context(), "Short URL created successfully",
+		slog.String("code", link.Code),
+		slog.String("original_url", req.URL),
+	)
+
+	writeJSON(w, http.StatusCreated, h.linkResponse(r, link))
+}
+
+// CreateLinks shortens a JSON array of requests. Items succeed or fail on
+// their own; each result carries the status it would have had alone.
+func (h *Handler) CreateLinks(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, int64(h.opts.MaxBatchSize)*maxBatchItemBytes)
+
+	var reqs []ShortenRequest
+	if err := json.NewDecoder(body).Decode(&reqs); err != nil {
+		logger.Warn(r.Context(), "Invalid request body", slog.String("error", err.Error()))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status, message := errorStatus(err)
+			writeJSONError(w, status, message)
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body, expected an array of links")
+		return
+	}
+
+	if len(reqs) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Batch must not be empty")
+		return
+	}
+	if len(reqs) > h.opts.MaxBatchSize {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "Batch must not exceed "+strconv.Itoa(h.opts.MaxBatchSize)+" links")
+		return
+	}
+
+	serviceReqs := make([]shortener.Request, len(reqs))
+	for i, req := range reqs {
+		serviceReqs[i] = req.serviceRequest(r)
+	}
+
+	results, err := h.service.ShortenBatch(r.Context(), serviceReqs)
+	if err != nil {
+		h.writeAPIError(w, r, "Failed to create short URLs", err)
+		return
+	}
+
+	resp := BatchResponse{Results: make([]BatchResult, len(results))}
+	created := 0
+	for i, result := range results {
+		item := BatchResult{Index: i, Status: http.StatusCreated}
+		if result.Err != nil {
+			item.Status, item.Error = errorStatus(result.Err)
+		} else {
+			link := h.linkResponse(r, result.Link)
+			item.Link = &link
+			created++
+		}
+		resp.Results[i] = item
+	}
+
+	logger.Info(r.Context(), "Short URL batch processed",
+		slog.Int("requested", len(reqs)),
+		slog.Int("created", created),
+	)
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetLink(w http.ResponseWriter, r *http.Request) {
+	link, err := h.ownedLink(r, r.PathValue("code"))
+	if err != nil {
+		h.writeAPIError(w, r, "Failed to load link", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.linkResponse(r, link))
+}
+
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultListLimit)
+	if err != nil || limit < 1 || limit > maxListLimit {
+		writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxListLimit))
+		return
+	}
+
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSONError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	opts, err := listFilters(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.Limit, opts.Offset = limit, offset
+
+	links, err := h.service.List(r.Context(), opts)
+	if err != nil {
+		h.writeAPIError(w, r, "Failed to list links", err)
+		return
+	}
+
+	resp := ListResponse{
+		Links:  make([]LinkResponse, 0, len(links)),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, link := range links {
+		resp.Links = append(resp.Links, h.linkResponse(r, link))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	if _, err := h.ownedLink(r, code); err != nil {
+		h.writeAPIError(w, r, "Failed to delete link", err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), code); err != nil {
+		h.writeAPIError(w, r, "Failed to delete link", err)
+		return
+	}
+
+	logger.Info(r.Context(), "Short URL deleted", slog.String("code", code))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) LinkStats(w http.ResponseWriter, r *http.Request) {
+	link, err := h.ownedLink(r, r.PathValue("code"))
+	if err != nil {
+		h.writeAPIError(w, r, "Failed to load link stats", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, StatsResponse{
+		Code:          link.Code,
+		Clicks:        link.Clicks,
+		LastClickedAt: link.LastClickedAt,
+		CreatedAt:     link.CreatedAt,
+	})
+}
+
+// ownedLink loads a link the caller may manage: any link with an admin key,
+// otherwise only those of the key's owner. Other links are reported as not
+// found so their codes cannot be probed.
+func (h *Handler) ownedLink(r *http.Request, code string) (entity.Link, error) {
+	link, err := h.service.Get(r.Context(), code)
+	if err != nil {
+		return entity.Link{}, err
+	}
+
+	owner := auth.Owner(r.Context())
+	if !auth.IsAdmin(r.Context()) && (owner == "" || link.Owner != owner) {
+		return entity.Link{}, storage.ErrNotFound
+	}
+	return link, nil
+}
+
+func (h *Handler) linkResponse(r *http.Request, link entity.Link) LinkResponse {
+	return LinkResponse{
+		Code:          link.Code,
+		ShortURL:      h.shortURL(r, link.Code),
+		QRURL:         h.qrURL(r, link.Code),
+		OriginalURL:   link.OriginalURL,
+		Title:         link.Title,
+		Description:   link.Description,
+		FaviconURL:    link.FaviconURL,
+		ImageURL:      link.ImageURL,
+		CreatedAt:     link.CreatedAt,
+		Clicks:        link.Clicks,
+		LastClickedAt: link.LastClickedAt,
+		Owner:         link.Owner,
+		ExpiresAt:     link.ExpiresAt,
+		DisabledAt:    link.DisabledAt,
+		RedirectType:  h.redirectType(link),
+		ForwardPath:   link.ForwardPath,
+		ForwardQuery:  link.ForwardQuery,
+	}
+}
+
+func (h *Handler) writeAPIError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	status, message := errorStatus(err)
+
+	attrs := []any{slog.String("error", err.Error())}
+	if code := r.PathValue("code"); code != "" {
+		attrs = append(attrs, slog.String("code", code))
+	}
+	if status >= http.StatusInternalServerError {
+		logger.Error(r.Context(), msg, attrs...)
+	} else {
+		logger.Warn(r.Context(), msg, attrs...)
+	}
+
+	writeJSONError(w, status, message)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
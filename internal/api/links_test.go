@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/shortener"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+func TestCreateLinksBodyLimit(t *testing.T) {
+	h := NewHandler(shortener.NewService(storage.NewMemoryStorage(), shortener.Options{}), Options{MaxBatchSize: 2})
+	item := `{"url":"https://example.com/` + strings.Repeat("a", 1000) + `"}`
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"within the limit", "[" + item + "," + item + "]", http.StatusOK},
+		{"too many items", "[" + item + "," + item + "," + item + "]", http.StatusRequestEntityTooLarge},
+		{"too many bytes", `[{"url":"https://example.com/` + strings.Repeat("a", 2*maxBatchItemBytes) + `"}]`, http.StatusRequestEntityTooLarge},
+		{"not an array", `{"url":"https://example.com"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/links/batch", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.CreateLinks(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var previewTemplate = template.Must(template.ParseFS(templates, "templates/preview.html"))
+
+type previewPage struct {
+	ShortURL    string
+	OriginalURL string
+	Host        string
+	Title       string
+	Description string
+	CreatedAt   time.Time
+	Clicks      int64
+}
+
+// PreviewLink shows where a link leads instead of redirecting. It is served
+// at /{code}/preview and, through ResolveURL, at /{code}+. Previews do not
+// count as clicks.
+func (h *Handler) PreviewLink(w http.ResponseWriter, r *http.Request) {
+	h.preview(w, r, r.PathValue("code"))
+}
+
+func (h *Handler) preview(w http.ResponseWriter, r *http.Request, code string) {
+	link, err := h.service.Preview(r.Context(), code)
+	if err != nil {
+		logger.Warn(r.Context(), "Preview not shown",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		writeServiceError(w, err)
+		return
+	}
+
+	page := previewPage{
+		ShortURL:    h.shortURL(r, link.Code),
+		OriginalURL: link.OriginalURL,
+		Title:       link.Title,
+		Description: link.Description,
+		CreatedAt:   link.CreatedAt,
+		Clicks:      link.Clicks,
+	}
+	if u, err := url.Parse(link.OriginalURL); err == nil {
+		page.Host = u.Host
+	}
+
+	var buf bytes.Buffer
+	if err := previewTemplate.Execute(&buf, page); err != nil {
+		logger.Error(r.Context(), "Failed to render preview",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/qrcode"
+)
+
+// qrMaxAge is how long browsers may reuse a QR code. It is kept short and
+// private so a link that is disabled or expires stops being served soon.
+const qrMaxAge = 5 * time.Minute
+
+// QRCode renders a QR code for the short URL of a link, as PNG unless
+// format=svg. size, margin, level, fg and bg tune the image. Disabled and
+// expired links get no QR code.
+func (h *Handler) QRCode(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	format, opts, err := qrOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.Preview(r.Context(), code)
+	if err != nil {
+		logger.Warn(r.Context(), "QR code not generated",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		writeServiceError(w, err)
+		return
+	}
+
+	// Encode into a buffer so a failure can still be reported as an error.
+	var buf bytes.Buffer
+	if err := qrcode.Encode(&buf, h.shortURL(r, link.Code), format, opts); err != nil {
+		logger.Error(r.Context(), "Failed to generate QR code",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(qrMaxAge.Seconds())))
+	w.Write(buf.Bytes())
+}
+
+func qrOptions(r *http.Request) (qrcode.Format, qrcode.Options, error) {
+	opts := qrcode.DefaultOptions()
+
+	format, err := qrcode.ParseFormat(queryDefault(r, "format", string(qrcode.PNG)))
+	if err != nil {
+		return "", opts, err
+	}
+
+	if opts.Size, err = queryInt(r, "size", qrcode.DefaultSize); err != nil {
+		return "", opts, qrcode.ErrInvalidSize
+	}
+	if opts.Margin, err = queryInt(r, "margin", qrcode.DefaultMargin); err != nil {
+		return "", opts, qrcode.ErrInvalidMargin
+	}
+	if v := r.URL.Query().Get("level"); v != "" {
+		if opts.Level, err = qrcode.ParseLevel(v); err != nil {
+			return "", opts, err
+		}
+	}
+	if v := r.URL.Query().Get("fg"); v != "" {
+		if opts.Foreground, err = qrcode.ParseColor(v); err != nil {
+			return "", opts, err
+		}
+	}
+	if v := r.URL.Query().Get("bg"); v != "" {
+		if opts.Background, err = qrcode.ParseColor(v); err != nil {
+			return "", opts, err
+		}
+	}
+
+	return format, opts, opts.Validate()
+}
+
+// qrURL is where the QR code of a link is served.
+func (h *Handler) qrURL(r *http.Request, code string) string {
+	return h.shortURL(r, code) + "/qr"
+}
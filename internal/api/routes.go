@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Igorjr19/go-shorty/internal/auth"
+)
+
+// Middleware wraps a single route, e.g. with a rate limiter.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Register adds every route to mux. Reads and writes take separate
+// middleware so they can be limited independently; nil leaves them as is.
+// Routes that manage existing links are only added when Options.Auth is set,
+// since without it no request could carry a key.
+func (h *Handler) Register(mux *http.ServeMux, read, write Middleware) {
+	if read == nil {
+		read = passthrough
+	}
+	if write == nil {
+		write = passthrough
+	}
+	authed := h.opts.Auth
+	if authed == nil {
+		authed = passthrough
+	}
+	// Managing links always takes a key, even when creating them does not.
+	managed := func(next http.HandlerFunc) http.HandlerFunc {
+		return authed(requireKey(next))
+	}
+
+	mux.HandleFunc("POST /shorten", write(authed(h.ShortenURL)))
+	mux.HandleFunc("/{code}", read(h.ResolveURL))
+	mux.HandleFunc("/{code}/{rest...}", read(h.ResolveURL))
+	// More specific than the forwarding pattern, so GET /{code}/qr and
+	// /{code}/preview are never passed on to a destination.
+	mux.HandleFunc("GET /{code}/qr", read(h.QRCode))
+	mux.HandleFunc("GET /{code}/preview", read(h.PreviewLink))
+	mux.HandleFunc("POST /api/v1/links", write(authed(h.CreateLink)))
+	mux.HandleFunc("POST /api/v1/links/batch", write(authed(h.CreateLinks)))
+
+	if h.opts.Auth == nil {
+		return
+	}
+	mux.HandleFunc("GET /api/v1/links", read(managed(h.ListLinks)))
+	mux.HandleFunc("GET /api/v1/links/export", read(managed(h.ExportLinks)))
+	mux.HandleFunc("POST /api/v1/links/import", write(managed(h.ImportLinks)))
+	mux.HandleFunc("GET /api/v1/links/{code}", read(managed(h.GetLink)))
+	mux.HandleFunc("DELETE /api/v1/links/{code}", write(managed(h.DeleteLink)))
+	mux.HandleFunc("GET /api/v1/links/{code}/stats", read(managed(h.LinkStats)))
+}
+
+func requireKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Authenticated(r.Context()) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="go-shorty"`)
+			writeJSONError(w, http.StatusUnauthorized, "API key required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func passthrough(next http.HandlerFunc) http.HandlerFunc {
+	return next
+}
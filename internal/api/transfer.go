@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/auth"
+	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+	"github.com/Igorjr19/go-shorty/internal/transfer"
+)
+
+// ExportLinks streams every link matching the list filters as CSV or NDJSON.
+func (h *Handler) ExportLinks(w http.ResponseWriter, r *http.Request) {
+	format, err := transfer.ParseFormat(queryDefault(r, "format", string(transfer.CSV)))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts, err := listFilters(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+
+	// Once rows are streaming the status can no longer change, so a failure
+	// halfway only shows up as a truncated body and in the logs.
+	n, err := transfer.Export(r.Context(), h.service, transfer.NewWriter(w, format), opts)
+	if err != nil {
+		logger.Error(r.Context(), "Link export failed",
+			slog.Int("exported", n),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	logger.Info(r.Context(), "Links exported", slog.Int("exported", n), slog.String("format", string(format)))
+}
+
+// ImportLinks restores links from a CSV or NDJSON body, or from a Bitly or
+// YOURLS export, keeping their codes, timestamps and click counts. Links are
+// imported under the key's owner unless it is an admin key, which is also
+// the only kind allowed to overwrite existing links.
+func (h *Handler) ImportLinks(w http.ResponseWriter, r *http.Request) {
+	defaultFormat := transfer.CSV
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == transfer.NDJSON.ContentType() {
+		defaultFormat = transfer.NDJSON
+	}
+
+	format, err := transfer.ParseImportFormat(queryDefault(r, "format", string(defaultFormat)))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	policy, err := transfer.ParseConflictPolicy(queryDefault(r, "on_conflict", string(transfer.Skip)))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	admin := auth.IsAdmin(r.Context())
+	if policy == transfer.Overwrite && !admin {
+		writeJSONError(w, http.StatusForbidden, "Only admin keys can overwrite existing links")
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(queryDefault(r, "dry_run", "false"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "dry_run must be true or false")
+		return
+	}
+
+	opts := transfer.ImportOptions{
+		OnConflict: policy,
+		DryRun:     dryRun,
+	}
+	if !admin {
+		opts.Owner = auth.Owner(r.Context())
+	}
+
+	body := http.MaxBytesReader(w, r.Body, h.opts.MaxImportBytes)
+	report, err := transfer.Import(r.Context(), h.service, transfer.NewReader(body, format), opts)
+
+	logger.Info(r.Context(), "Links imported",
+		slog.Bool("dry_run", dryRun),
+		slog.Int("imported", report.Imported),
+		slog.Int("overwritten", report.Overwritten),
+		slog.Int("skipped", len(report.Skipped)),
+		slog.Int("invalid", len(report.Invalid)),
+	)
+
+	if errors.Is(err, transfer.ErrConflict) {
+		writeJSON(w, http.StatusConflict, struct {
+			Error string `json:"error"`
+			transfer.Report
+		}{err.Error(), report})
+		return
+	}
+	if err != nil {
+		h.writeAPIError(w, r, "Link import failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// listFilters reads the filters shared by listing and export. Only admin
+// keys may pick an owner; other keys only ever see their own links.
+func listFilters(r *http.Request) (storage.ListOptions, error) {
+	q := r.URL.Query()
+	opts := storage.ListOptions{
+		Query: q.Get("q"),
+		Owner: q.Get("owner"),
+	}
+	if !auth.IsAdmin(r.Context()) {
+		opts.Owner = auth.Owner(r.Context())
+	}
+
+	var err error
+	if opts.OnlyDisabled, err = strconv.ParseBool(queryDefault(r, "disabled", "false")); err != nil {
+		return opts, fmt.Errorf("disabled must be true or false")
+	}
+
+	expired, err := strconv.ParseBool(queryDefault(r, "expired", "false"))
+	if err != nil {
+		return opts, fmt.Errorf("expired must be true or false")
+	}
+	if expired {
+		now := time.Now()
+		opts.ExpiredBefore = &now
+	}
+
+	for _, t := range []struct {
+		key string
+		dst **time.Time
+	}{
+		{"created_after", &opts.CreatedAfter},
+		{"created_before", &opts.CreatedBefore},
+	} {
+		value := q.Get(t.key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return opts, fmt.Errorf("%s must be an RFC 3339 time", t.key)
+		}
+		*t.dst = &parsed
+	}
+
+	return opts, nil
+}
+
+func queryDefault(r *http.Request, key, defaultValue string) string {
+	if value := r.URL.Query().Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/google/uuid"
+)
+
+const (
+	keyPrefix    = "gs_"
+	prefixLength = len(keyPrefix) + 8
+)
+
+type keyKey struct{}
+
+// NewAPIKey generates a key for owner and returns it along with the plain
+// text token, which is shown once and never stored.
+func NewAPIKey(name, owner string, now time.Time) (entity.APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return entity.APIKey{}, "", err
+	}
+	token := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := entity.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Owner:     owner,
+		Prefix:    token[:prefixLength],
+		Hash:      HashKey(token),
+		CreatedAt: now,
+	}
+	return key, token, nil
+}
+
+func HashKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithKey records the API key a request was authenticated with.
+func WithKey(ctx context.Context, key entity.APIKey) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// Authenticated reports whether the request was made with an API key.
+func Authenticated(ctx context.Context) bool {
+	_, ok := ctx.Value(keyKey{}).(entity.APIKey)
+	return ok
+}
+
+// Owner returns the owner of the API key the request was made with, or ""
+// for anonymous requests.
+func Owner(ctx context.Context) string {
+	key, _ := ctx.Value(keyKey{}).(entity.APIKey)
+	return key.Owner
+}
+
+// IsAdmin reports whether the request was made with an admin key.
+func IsAdmin(ctx context.Context) bool {
+	key, _ := ctx.Value(keyKey{}).(entity.APIKey)
+	return key.Admin
+}
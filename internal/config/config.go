@@ -0,0 +1,215 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+type Config struct {
+	Environment string          `yaml:"environment" toml:"environment" env:"ENVIRONMENT"`
+	Server      ServerConfig    `yaml:"server" toml:"server"`
+	Auth        AuthConfig      `yaml:"auth" toml:"auth"`
+	Reload      ReloadConfig    `yaml:"reload" toml:"reload"`
+	Log         LogConfig       `yaml:"log" toml:"log" reload:"true"`
+	Database    DatabaseConfig  `yaml:"database" toml:"database"`
+	Migrations  MigrationConfig `yaml:"migrations" toml:"migrations"`
+	RateLimit   RateLimitConfig `yaml:"rate_limit" toml:"rate_limit" reload:"true"`
+	Shortener   ShortenerConfig `yaml:"shortener" toml:"shortener"`
+	Metadata    MetadataConfig  `yaml:"metadata" toml:"metadata"`
+}
+
+type ServerConfig struct {
+	Port    string `yaml:"port" toml:"port" env:"PORT"`
+	BaseURL string `yaml:"base_url" toml:"base_url" env:"BASE_URL"`
+}
+
+type AuthConfig struct {
+	// RequireAPIKey rejects API requests without a key. Keys are always
+	// checked when sent, so links are attributed to their owner either way.
+	RequireAPIKey bool `yaml:"require_api_key" toml:"require_api_key" env:"REQUIRE_API_KEY"`
+}
+
+type ReloadConfig struct {
+	WatchInterval time.Duration `yaml:"watch_interval" toml:"watch_interval" env:"CONFIG_WATCH_INTERVAL"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+}
+
+type MigrationConfig struct {
+	Dir         string        `yaml:"dir" toml:"dir" env:"MIGRATIONS_DIR"`
+	OnStartup   bool          `yaml:"on_startup" toml:"on_startup" env:"MIGRATE_ON_STARTUP"`
+	LockTimeout time.Duration `yaml:"lock_timeout" toml:"lock_timeout" env:"MIGRATIONS_LOCK_TIMEOUT"`
+}
+
+type RateLimitConfig struct {
+	Read  RateLimitPolicy `yaml:"read" toml:"read" env:"RATE_LIMIT_READ"`
+	Write RateLimitPolicy `yaml:"write" toml:"write" env:"RATE_LIMIT_WRITE"`
+}
+
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests" toml:"requests" env:"REQUESTS"`
+	Window   time.Duration `yaml:"window" toml:"window" env:"WINDOW"`
+}
+
+type ShortenerConfig struct {
+	CodeLength   int           `yaml:"code_length" toml:"code_length" env:"SHORTENER_CODE_LENGTH"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"STORAGE_WRITE_TIMEOUT"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"STORAGE_READ_TIMEOUT"`
+	BatchMaxSize int           `yaml:"batch_max_size" toml:"batch_max_size" env:"SHORTENER_BATCH_MAX_SIZE"`
+	// ImportMaxBytes caps the body of an import request.
+	ImportMaxBytes int `yaml:"import_max_bytes" toml:"import_max_bytes" env:"SHORTENER_IMPORT_MAX_BYTES"`
+	// RedirectType is the status of links created without one.
+	RedirectType int `yaml:"redirect_type" toml:"redirect_type" env:"SHORTENER_REDIRECT_TYPE"`
+
+	BlockedDomains  []string `yaml:"blocked_domains" toml:"blocked_domains" env:"SHORTENER_BLOCKED_DOMAINS" reload:"true"`
+	ReservedAliases []string `yaml:"reserved_aliases" toml:"reserved_aliases" env:"SHORTENER_RESERVED_ALIASES" reload:"true"`
+}
+
+type MetadataConfig struct {
+	// Enabled fetches the title, description, favicon and Open Graph image
+	// of each destination in the background. It is off by default since it
+	// makes the server request every URL it is given.
+	Enabled         bool          `yaml:"enabled" toml:"enabled" env:"METADATA_ENABLED"`
+	Workers         int           `yaml:"workers" toml:"workers" env:"METADATA_WORKERS"`
+	Timeout         time.Duration `yaml:"timeout" toml:"timeout" env:"METADATA_TIMEOUT"`
+	MaxBytes        int           `yaml:"max_bytes" toml:"max_bytes" env:"METADATA_MAX_BYTES"`
+	MaxRedirects    int           `yaml:"max_redirects" toml:"max_redirects" env:"METADATA_MAX_REDIRECTS"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" toml:"refresh_interval" env:"METADATA_REFRESH_INTERVAL"`
+	MaxAge          time.Duration `yaml:"max_age" toml:"max_age" env:"METADATA_MAX_AGE"`
+}
+
+func Default() Config {
+	return Config{
+		Environment: "development",
+		Server: ServerConfig{
+			Port: "8080",
+		},
+		Reload: ReloadConfig{
+			WatchInterval: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:           "localhost",
+			Port:           "5432",
+			Name:           "shorty",
+			SSLMode:        "disable",
+			ConnectTimeout: "20",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			RetryInitialBackoff: 500 * time.Millisecond,
+			RetryMaxBackoff:     10 * time.Second,
+			RetryTimeout:        time.Minute,
+
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Migrations: MigrationConfig{
+			LockTimeout: time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Read:  RateLimitPolicy{Requests: 10, Window: time.Minute},
+			Write: RateLimitPolicy{Requests: 1000, Window: time.Minute},
+		},
+		Shortener: ShortenerConfig{
+			CodeLength:   6,
+			WriteTimeout: 5 * time.Second,
+			ReadTimeout:  2 * time.Second,
+			BatchMaxSize: 100,
+			RedirectType: 302,
+
+			ImportMaxBytes: 32 << 20,
+
+			ReservedAliases: []string{"api", "shorten", "metrics", "health", "admin", "static"},
+		},
+		Metadata: MetadataConfig{
+			Enabled:         false,
+			Workers:         4,
+			Timeout:         5 * time.Second,
+			MaxBytes:        1 << 20,
+			MaxRedirects:    5,
+			RefreshInterval: time.Hour,
+			MaxAge:          7 * 24 * time.Hour,
+		},
+	}
+}
+
+// Validate reports every problem with the configuration at once so a broken
+// deployment can be fixed in a single pass.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Environment != "", "environment must not be empty")
+	check(c.Reload.WatchInterval >= 0, "reload.watch_interval must not be negative")
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port <= 65535, "server.port must be a number between 1 and 65535, got %q", c.Server.Port)
+
+	if c.Server.BaseURL != "" {
+		u, err := url.Parse(c.Server.BaseURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "server.base_url must be an absolute http or https URL, got %q", c.Server.BaseURL)
+	}
+
+	if c.Log.Level != "" {
+		var level slog.Level
+		check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level must be one of debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	db := c.Database
+	check(db.URL != "" || db.Host != "", "database.url or database.host must be set")
+	check(db.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(db.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	check(db.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(db.ConnMaxIdleTime >= 0, "database.conn_max_idle_time must not be negative")
+	check(db.RetryInitialBackoff > 0, "database.retry_initial_backoff must be positive")
+	check(db.RetryMaxBackoff >= db.RetryInitialBackoff, "database.retry_max_backoff must not be lower than database.retry_initial_backoff")
+	check(db.RetryTimeout >= 0, "database.retry_timeout must not be negative")
+	check(db.SlowQueryThreshold >= 0, "database.slow_query_threshold must not be negative")
+
+	check(c.Migrations.LockTimeout >= 0, "migrations.lock_timeout must not be negative")
+	check(!c.Migrations.OnStartup || db.MaxOpenConns != 1, "database.max_open_conns must be at least 2 with migrations.on_startup, since the migration lock holds a connection")
+
+	policies := []struct {
+		name   string
+		policy RateLimitPolicy
+	}{
+		{"read", c.RateLimit.Read},
+		{"write", c.RateLimit.Write},
+	}
+	for _, p := range policies {
+		check(p.policy.Requests > 0, "rate_limit.%s.requests must be positive", p.name)
+		check(p.policy.Window > 0, "rate_limit.%s.window must be positive", p.name)
+	}
+
+	check(c.Shortener.CodeLength >= 4 && c.Shortener.CodeLength <= 32, "shortener.code_length must be between 4 and 32, got %d", c.Shortener.CodeLength)
+	check(c.Shortener.BatchMaxSize >= 1 && c.Shortener.BatchMaxSize <= 1000, "shortener.batch_max_size must be between 1 and 1000, got %d", c.Shortener.BatchMaxSize)
+	check(c.Shortener.ImportMaxBytes > 0, "shortener.import_max_bytes must be positive, got %d", c.Shortener.ImportMaxBytes)
+	check(c.Shortener.RedirectType != 0 && entity.ValidRedirectType(c.Shortener.RedirectType), "shortener.redirect_type must be 301, 302, 307 or 308, got %d", c.Shortener.RedirectType)
+	check(c.Shortener.WriteTimeout >= 0, "shortener.write_timeout must not be negative")
+	check(c.Shortener.ReadTimeout >= 0, "shortener.read_timeout must not be negative")
+
+	meta := c.Metadata
+	check(meta.Workers >= 1 && meta.Workers <= 64, "metadata.workers must be between 1 and 64, got %d", meta.Workers)
+	check(meta.Timeout > 0, "metadata.timeout must be positive")
+	check(meta.MaxBytes >= 1024, "metadata.max_bytes must be at least 1024, got %d", meta.MaxBytes)
+	check(meta.MaxRedirects >= 1 && meta.MaxRedirects <= 20, "metadata.max_redirects must be between 1 and 20, got %d", meta.MaxRedirects)
+	check(meta.RefreshInterval > 0, "metadata.refresh_interval must be positive")
+	check(meta.MaxAge > 0, "metadata.max_age must be positive")
+
+	return errors.Join(errs...)
+}
@@ -5,44 +5,158 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
-	"os"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/Igorjr19/go-shorty/internal/logger"
 	_ "github.com/lib/pq"
 )
 
-func ConnectDB() *sql.DB {
-	dbHost := os.Getenv("PGHOST")
-	dbPort := os.Getenv("PGPORT")
-	dbUser := os.Getenv("PGUSER")
-	dbPassword := os.Getenv("PGPASSWORD")
-	dbName := os.Getenv("PGDATABASE")
-	dbUseSSL := os.Getenv("PGSSLMODE")
-	dbConnectTimeout := os.Getenv("PGCONNECT_TIMEOUT")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s connect_timeout=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbUseSSL, dbConnectTimeout)
-
-	ctx := context.Background()
-	logger.Info(ctx, "Connecting to database",
-		slog.String("host", dbHost),
-		slog.String("port", dbPort),
-		slog.String("database", dbName),
-		slog.String("user", dbUser),
-	)
+type DatabaseConfig struct {
+	URL            string `yaml:"url" toml:"url" env:"DATABASE_URL" secret:"true"`
+	Host           string `yaml:"host" toml:"host" env:"PGHOST"`
+	Port           string `yaml:"port" toml:"port" env:"PGPORT"`
+	User           string `yaml:"user" toml:"user" env:"PGUSER"`
+	Password       string `yaml:"password" toml:"password" env:"PGPASSWORD" secret:"true"`
+	Name           string `yaml:"name" toml:"name" env:"PGDATABASE"`
+	SSLMode        string `yaml:"ssl_mode" toml:"ssl_mode" env:"PGSSLMODE"`
+	ConnectTimeout string `yaml:"connect_timeout" toml:"connect_timeout" env:"PGCONNECT_TIMEOUT"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	RetryInitialBackoff time.Duration `yaml:"retry_initial_backoff" toml:"retry_initial_backoff" env:"DB_CONNECT_RETRY_INITIAL_BACKOFF"`
+	RetryMaxBackoff     time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff" env:"DB_CONNECT_RETRY_MAX_BACKOFF"`
+	RetryTimeout        time.Duration `yaml:"retry_timeout" toml:"retry_timeout" env:"DB_CONNECT_RETRY_TIMEOUT"`
+
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+}
+
+// DSN returns DATABASE_URL when set, otherwise a key/value connection string
+// built from the individual PG* settings.
+func (c DatabaseConfig) DSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	params := []struct{ key, value string }{
+		{"host", c.Host},
+		{"port", c.Port},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Name},
+		{"sslmode", c.SSLMode},
+		{"connect_timeout", c.ConnectTimeout},
+	}
 
-	db, err := sql.Open("postgres", connStr)
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+func (c DatabaseConfig) logAttrs() []any {
+	if c.URL == "" {
+		return []any{
+			slog.String("host", c.Host),
+			slog.String("port", c.Port),
+			slog.String("database", c.Name),
+			slog.String("user", c.User),
+		}
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return []any{slog.String("url", "[unparseable]")}
+	}
+	return []any{
+		slog.String("host", u.Hostname()),
+		slog.String("port", u.Port()),
+		slog.String("database", strings.TrimPrefix(u.Path, "/")),
+		slog.String("user", u.User.Username()),
+	}
+}
+
+func ConnectDB(ctx context.Context, cfg DatabaseConfig) (*sql.DB, error) {
+	logger.Info(ctx, "Connecting to database", cfg.logAttrs()...)
+
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		logger.Error(ctx, "Failed to connect to database", slog.String("error", err.Error()))
-		panic(fmt.Sprintf("Unable to connect to database: %v", err))
+		return nil, fmt.Errorf("unable to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := pingWithRetry(ctx, db, cfg); err != nil {
 		logger.Error(ctx, "Failed to ping database", slog.String("error", err.Error()))
-		panic(fmt.Sprintf("Unable to ping database: %v", err))
+		db.Close()
+		return nil, err
 	}
 
-	logger.Info(ctx, "Database connection established successfully")
+	logger.Info(ctx, "Database connection established successfully",
+		slog.Int("max_open_conns", cfg.MaxOpenConns),
+		slog.Int("max_idle_conns", cfg.MaxIdleConns),
+		slog.Duration("conn_max_lifetime", cfg.ConnMaxLifetime),
+	)
+
+	return db, nil
+}
 
-	return db
+func pingWithRetry(ctx context.Context, db *sql.DB, cfg DatabaseConfig) error {
+	if cfg.RetryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.RetryTimeout)
+		defer cancel()
+	}
+
+	backoff := cfg.RetryInitialBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		logger.Warn(ctx, "Database not ready, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("unable to ping database after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if cfg.RetryMaxBackoff > 0 && backoff > cfg.RetryMaxBackoff {
+			backoff = cfg.RetryMaxBackoff
+		}
+	}
 }
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const secretMask = "********"
+
+// Loader builds a Config from defaults, an optional YAML or TOML file,
+// environment variables and command-line flags, in increasing precedence.
+type Loader struct {
+	file        string
+	printConfig bool
+	overrides   map[string]string
+}
+
+// NewLoader registers the configuration flags on fs. Flags must be parsed
+// before calling Load.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{overrides: make(map[string]string)}
+
+	fs.StringVar(&l.file, "config", os.Getenv("CONFIG_FILE"), "Path to a YAML or TOML configuration file (env CONFIG_FILE)")
+	fs.BoolVar(&l.printConfig, "print-config", false, "Print the effective configuration with secrets masked and exit")
+
+	defaults := Default()
+	for _, f := range fields(&defaults) {
+		usage := "Overrides " + f.key
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		set := func(value string) error {
+			l.overrides[f.key] = value
+			return nil
+		}
+		if _, ok := f.value.(*bool); ok {
+			fs.BoolFunc(f.flag, usage, set)
+		} else {
+			fs.Func(f.flag, usage, set)
+		}
+	}
+
+	return l
+}
+
+func (l *Loader) PrintConfig() bool {
+	return l.printConfig
+}
+
+func (l *Loader) File() string {
+	return l.file
+}
+
+// Load returns the effective configuration. Every parse and validation error
+// is reported at once, joined. Parse errors come with a nil Config;
+// validation errors alone are returned alongside the Config so callers can
+// still print it.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+	var parseErrs []error
+
+	if l.file != "" {
+		if err := loadFile(l.file, &cfg); err != nil {
+			parseErrs = append(parseErrs, err)
+		}
+	}
+
+	for _, f := range fields(&cfg) {
+		if f.env == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(f.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := setValue(f.value, value); err != nil {
+			parseErrs = append(parseErrs, fmt.Errorf("invalid value for %s: %w", f.env, err))
+		}
+	}
+
+	for _, f := range fields(&cfg) {
+		value, ok := l.overrides[f.key]
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, value); err != nil {
+			parseErrs = append(parseErrs, fmt.Errorf("invalid value for -%s: %w", f.flag, err))
+		}
+	}
+
+	if len(parseErrs) > 0 {
+		return nil, errors.Join(append(parseErrs, cfg.Validate())...)
+	}
+	return &cfg, cfg.Validate()
+}
+
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys in config file %s: %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q: use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	return nil
+}
+
+// Print writes the configuration as YAML with every secret masked.
+func (c *Config) Print(w io.Writer) error {
+	masked := *c
+	for _, f := range fields(&masked) {
+		if !f.secret {
+			continue
+		}
+		if s, ok := f.value.(*string); ok && *s != "" {
+			*s = maskSecret(*s)
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(masked); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (f field) String() string {
+	value := reflect.ValueOf(f.value).Elem().Interface()
+	if s, ok := value.(string); ok && f.secret && s != "" {
+		return maskSecret(s)
+	}
+	return fmt.Sprint(value)
+}
+
+func maskSecret(value string) string {
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.User != nil {
+		return u.Redacted()
+	}
+	return secretMask
+}
+
+type field struct {
+	key        string
+	env        string
+	flag       string
+	secret     bool
+	reloadable bool
+	value      any
+}
+
+// fields walks the configuration struct and returns a pointer to every leaf
+// setting along with the names it can be overridden by.
+func fields(cfg *Config) []field {
+	return walk(reflect.ValueOf(cfg).Elem(), "", "", false)
+}
+
+func walk(v reflect.Value, keyPrefix, envPrefix string, reloadable bool) []field {
+	var result []field
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := name
+		if keyPrefix != "" {
+			key = keyPrefix + "." + name
+		}
+
+		env := sf.Tag.Get("env")
+		if env != "" && envPrefix != "" {
+			env = envPrefix + "_" + env
+		}
+
+		fieldReloadable := reloadable || sf.Tag.Get("reload") == "true"
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			result = append(result, walk(fv, key, env, fieldReloadable)...)
+			continue
+		}
+
+		result = append(result, field{
+			key:        key,
+			env:        env,
+			flag:       strings.ReplaceAll(key, "_", "-"),
+			secret:     sf.Tag.Get("secret") == "true",
+			reloadable: fieldReloadable,
+			value:      fv.Addr().Interface(),
+		})
+	}
+
+	return result
+}
+
+func setValue(target any, value string) error {
+	switch t := target.(type) {
+	case *string:
+		*t = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+		*t = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected a boolean, got %q", value)
+		}
+		*t = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 30s or 5m, got %q", value)
+		}
+		*t = d
+	case *[]string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*t = items
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
+)
+
+// Watch reloads the configuration on SIGHUP and, when a file is in use,
+// whenever its modification time changes. Only settings tagged as reloadable
+// are applied; onReload receives the merged configuration. Watch blocks until
+// ctx is done.
+func (l *Loader) Watch(ctx context.Context, current *Config, onReload func(*Config)) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	var tick <-chan time.Time
+	if l.file != "" && current.Reload.WatchInterval > 0 {
+		ticker := time.NewTicker(current.Reload.WatchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	lastModified := modTime(l.file)
+
+	for {
+		var trigger string
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			trigger = "signal"
+		case <-tick:
+			modified := modTime(l.file)
+			if modified.Equal(lastModified) {
+				continue
+			}
+			lastModified = modified
+			trigger = "file"
+		}
+
+		logger.Info(ctx, "Reloading configuration", slog.String("trigger", trigger))
+
+		next, changed := l.reload(ctx, current)
+		if !changed {
+			logger.Info(ctx, "Configuration reloaded, nothing to apply")
+			continue
+		}
+
+		onReload(next)
+		current = next
+	}
+}
+
+func (l *Loader) reload(ctx context.Context, current *Config) (*Config, bool) {
+	loaded, err := l.Load()
+	if err != nil {
+		logger.Error(ctx, "Configuration reload failed, keeping current settings", slog.String("error", err.Error()))
+		return current, false
+	}
+
+	merged := *current
+	changed := false
+
+	currentFields := fields(&merged)
+	loadedFields := fields(loaded)
+
+	for i, f := range currentFields {
+		next := loadedFields[i]
+		oldValue := reflect.ValueOf(f.value).Elem()
+		newValue := reflect.ValueOf(next.value).Elem()
+
+		if reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			continue
+		}
+
+		if !f.reloadable {
+			logger.Warn(ctx, "Configuration change requires a restart, ignoring",
+				slog.String("key", f.key),
+				slog.String("current", f.String()),
+				slog.String("requested", next.String()),
+			)
+			continue
+		}
+
+		logger.Info(ctx, "Configuration changed",
+			slog.String("key", f.key),
+			slog.String("old", f.String()),
+			slog.String("new", next.String()),
+		)
+
+		oldValue.Set(newValue)
+		changed = true
+	}
+
+	return &merged, changed
+}
+
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
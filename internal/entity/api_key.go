@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// APIKey is a credential for the HTTP API. Only the hash of the key is
+// stored; Prefix keeps enough of it to tell keys apart in listings.
+type APIKey struct {
+	ID        string
+	Name      string
+	Owner     string
+	Prefix    string
+	Hash      string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+	// Admin keys manage every link, not just their owner's, and can
+	// overwrite existing links on import.
+	Admin bool
+}
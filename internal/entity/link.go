@@ -1,9 +1,72 @@
 package entity
 
-import "time"
+import (
+	"net/http"
+	"time"
+)
 
 type Link struct {
-	Code        string
-	OriginalURL string
-	CreatedAt   time.Time
+	Code          string
+	OriginalURL   string
+	Title         string
+	CreatedAt     time.Time
+	Clicks        int64
+	LastClickedAt *time.Time
+	Owner         string
+	DisabledAt    *time.Time
+	ExpiresAt     *time.Time
+	// RedirectType is the status the link redirects with, zero for the
+	// server default.
+	RedirectType int
+	// ForwardPath appends the rest of the visited path to the destination,
+	// so /abc/docs leads to the destination followed by /docs.
+	ForwardPath  bool
+	ForwardQuery QueryForwarding
+
+	Description       string
+	FaviconURL        string
+	ImageURL          string
+	MetadataFetchedAt *time.Time
+}
+
+// QueryForwarding is how the query string of a visit is passed on to the
+// destination.
+type QueryForwarding string
+
+const (
+	// QueryDropped ignores the query of the visit.
+	QueryDropped QueryForwarding = ""
+	// QueryPreferRequest merges both queries; for a key in both, the values
+	// of the visit replace those of the destination.
+	QueryPreferRequest QueryForwarding = "prefer_request"
+	// QueryPreferLink merges both queries; for a key in both, the values of
+	// the destination are kept.
+	QueryPreferLink QueryForwarding = "prefer_link"
+)
+
+func (q QueryForwarding) Valid() bool {
+	return q == QueryDropped || q == QueryPreferRequest || q == QueryPreferLink
+}
+
+// Metadata describes the page a link leads to.
+type Metadata struct {
+	Title       string
+	Description string
+	FaviconURL  string
+	ImageURL    string
+}
+
+// Expired reports whether the link has an expiry at or before now.
+func (l Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+// ValidRedirectType reports whether status can be used as a RedirectType.
+func ValidRedirectType(status int) bool {
+	switch status {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
 }
@@ -2,8 +2,13 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,23 +18,41 @@ const (
 	RequestIDKey contextKey = "request_id"
 )
 
-var log *slog.Logger
+const maxQueryLogLength = 1000
+
+var log = slog.Default()
+
+var (
+	level        = new(slog.LevelVar)
+	defaultLevel = slog.LevelInfo
+)
+
+var slowQueryThreshold atomic.Int64
 
 func Init(env string) {
+	InitWithWriter(env, os.Stdout)
+}
+
+// InitWithWriter is Init with a custom destination, for command-line tools
+// that keep stdout for their own output.
+func InitWithWriter(env string, w io.Writer) {
 	var handler slog.Handler
 
+	defaultLevel = getLogLevel(env)
+	level.Set(defaultLevel)
+
 	opts := &slog.HandlerOptions{
-		Level:     getLogLevel(env),
+		Level:     level,
 		AddSource: env == "development",
 	}
 
 	switch env {
 	case "production":
 
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(w, opts)
 	default:
 
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		handler = slog.NewTextHandler(w, opts)
 	}
 
 	log = slog.New(handler)
@@ -47,6 +70,22 @@ func getLogLevel(env string) slog.Level {
 	}
 }
 
+// SetLevel overrides the level chosen by Init, or restores it when name is
+// empty. It is safe to call while other goroutines are logging.
+func SetLevel(name string) error {
+	if name == "" {
+		level.Set(defaultLevel)
+		return nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
 func WithRequestID(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, RequestIDKey, requestID)
 }
@@ -110,16 +149,82 @@ func RateLimitExceeded(ctx context.Context, ip string, limit int, window time.Du
 	)
 }
 
-func DatabaseQuery(ctx context.Context, query string, duration time.Duration, err error) {
+func SetSlowQueryThreshold(threshold time.Duration) {
+	slowQueryThreshold.Store(int64(threshold))
+}
+
+func DatabaseQuery(ctx context.Context, query string, args []any, rowsAffected int64, duration time.Duration, err error) {
 	attrs := []any{
-		slog.String("query", query),
+		slog.String("query", compactQuery(query)),
 		slog.Duration("duration_ms", duration),
 	}
 
-	if err != nil {
+	if len(args) > 0 {
+		attrs = append(attrs, slog.Any("args", RedactArgs(args)))
+	}
+
+	if rowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64("rows_affected", rowsAffected))
+	}
+
+	threshold := time.Duration(slowQueryThreshold.Load())
+
+	switch {
+	case err != nil:
 		attrs = append(attrs, slog.String("error", err.Error()))
 		Error(ctx, "Database query failed", attrs...)
-	} else {
+	case threshold > 0 && duration >= threshold:
+		attrs = append(attrs, slog.Duration("slow_query_threshold", threshold))
+		Warn(ctx, "Slow database query", attrs...)
+	default:
 		Debug(ctx, "Database query executed", attrs...)
 	}
 }
+
+// RedactArgs makes query parameters safe to log. URLs keep only their scheme
+// and host, since paths and query strings regularly carry access tokens.
+func RedactArgs(args []any) []any {
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		redacted[i] = redactArg(arg)
+	}
+	return redacted
+}
+
+func redactArg(arg any) any {
+	switch v := arg.(type) {
+	case nil:
+		return nil
+	case string:
+		return redactString(v)
+	case []byte:
+		return fmt.Sprintf("[%d bytes]", len(v))
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64, time.Time, time.Duration:
+		return v
+	case fmt.Stringer:
+		return redactString(v.String())
+	default:
+		return fmt.Sprintf("[%T]", v)
+	}
+}
+
+func redactString(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return s
+	}
+
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" {
+		return s
+	}
+
+	return u.Scheme + "://" + u.Host + "/[REDACTED]"
+}
+
+func compactQuery(query string) string {
+	compacted := strings.Join(strings.Fields(query), " ")
+	if len(compacted) > maxQueryLogLength {
+		return compacted[:maxQueryLogLength] + "..."
+	}
+	return compacted
+}
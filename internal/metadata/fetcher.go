@@ -0,0 +1,183 @@
+// Package metadata fetches the pages links lead to and keeps their title,
+// description, favicon and Open Graph image on the links.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxBytes     = 1 << 20
+	defaultMaxRedirects = 5
+)
+
+var (
+	ErrForbiddenAddress = errors.New("address is not publicly routable")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrNotHTML          = errors.New("destination is not an HTML page")
+)
+
+// Shared address space and the like are not covered by the netip helpers.
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+type Options struct {
+	// Timeout bounds a whole fetch, redirects and body included.
+	Timeout time.Duration
+	// MaxBytes is how much of a page is read looking for metadata.
+	MaxBytes     int64
+	MaxRedirects int
+	UserAgent    string
+	// AllowPrivate lets the fetcher reach loopback and private addresses.
+	// It exists for tests against a local server and must stay off in
+	// production, where it would expose internal services.
+	AllowPrivate bool
+}
+
+// Fetcher reads metadata from public web pages. Every connection is checked
+// after DNS resolution, so names pointing at internal addresses are refused
+// too, whether given directly or reached through a redirect.
+type Fetcher struct {
+	client *http.Client
+	opts   Options
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	var control func(network, address string, c syscall.RawConn) error
+	if !opts.AllowPrivate {
+		control = checkAddress
+	}
+	return newFetcher(opts, control)
+}
+
+// newFetcher checks every connection with control, when set.
+func newFetcher(opts Options, control func(network, address string, c syscall.RawConn) error) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxBytes
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = defaultMaxRedirects
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "go-shorty metadata fetcher"
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout, Control: control}
+
+	transport := &http.Transport{
+		// No proxy: it would connect on our behalf and skip the address check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Fetcher{
+		opts: opts,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > opts.MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkScheme(req.URL)
+			},
+		},
+	}
+}
+
+// Fetch downloads rawURL and returns the metadata found in its head.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (entity.Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return entity.Metadata{}, err
+	}
+	if err := checkScheme(u); err != nil {
+		return entity.Metadata{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return entity.Metadata{}, err
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return entity.Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return entity.Metadata{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return entity.Metadata{}, fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+
+	return parse(io.LimitReader(resp.Body, f.opts.MaxBytes), resp.Request.URL), nil
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// checkAddress runs right before each connection, on the resolved address.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr := addrPort.Addr().Unmap()
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::1", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr error
+	}{
+		{"93.184.216.34:443", nil},
+		{"127.0.0.1:80", ErrForbiddenAddress},
+		{"10.0.0.1:443", ErrForbiddenAddress},
+		{"[::ffff:127.0.0.1]:80", ErrForbiddenAddress},
+		{"[::1]:8080", ErrForbiddenAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := checkAddress("tcp", tt.address, nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkAddress(%s) = %v, want %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func htmlHandler(page string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(htmlHandler(`<html><head><title>Hello</title><link rel="icon" href="/icon.png"></head><body></body></html>`))
+	defer srv.Close()
+
+	meta, err := NewFetcher(Options{AllowPrivate: true}).Fetch(context.Background(), srv.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Hello" {
+		t.Errorf("Title = %q, want %q", meta.Title, "Hello")
+	}
+	if want := srv.URL + "/icon.png"; meta.FaviconURL != want {
+		t.Errorf("FaviconURL = %q, want %q", meta.FaviconURL, want)
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(htmlHandler(`<title>internal</title>`))
+	defer srv.Close()
+
+	_, err := NewFetcher(Options{}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fetch() = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+func TestFetchRefusesPrivateRedirects(t *testing.T) {
+	internal := httptest.NewServer(htmlHandler(`<title>internal</title>`))
+	defer internal.Close()
+	public := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer public.Close()
+
+	// Both servers listen on loopback, so treat the first one as public and
+	// leave every other address to the real check.
+	publicAddr := public.Listener.Addr().String()
+	f := newFetcher(Options{}, func(network, address string, c syscall.RawConn) error {
+		if address == publicAddr {
+			return nil
+		}
+		return checkAddress(network, address, c)
+	})
+
+	_, err := f.Fetch(context.Background(), public.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fetch() = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hop int
+		fmt.Sscanf(r.URL.Path, "/%d", &hop)
+		if hop < 3 {
+			http.Redirect(w, r, fmt.Sprintf("%s/%d", srv.URL, hop+1), http.StatusFound)
+			return
+		}
+		htmlHandler(`<title>arrived</title>`)(w, r)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		maxRedirects int
+		wantErr      error
+	}{
+		{"within the limit", 3, nil},
+		{"over the limit", 2, ErrTooManyRedirects},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFetcher(Options{AllowPrivate: true, MaxRedirects: tt.maxRedirects})
+			_, err := f.Fetch(context.Background(), srv.URL+"/0")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Fetch() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchReadsAtMostMaxBytes(t *testing.T) {
+	padding := "<!--" + strings.Repeat("x", 4096) + "-->"
+	srv := httptest.NewServer(htmlHandler(`<html><head>` + padding + `<title>too far</title></head></html>`))
+	defer srv.Close()
+
+	meta, err := NewFetcher(Options{AllowPrivate: true, MaxBytes: 1024}).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "" {
+		t.Errorf("Title = %q, read past MaxBytes", meta.Title)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := NewFetcher(Options{AllowPrivate: true, Timeout: 100 * time.Millisecond}).Fetch(context.Background(), srv.URL)
+	if err == nil {
+		t.Fatal("Fetch() succeeded against a stalled server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fetch() took %s with a 100ms timeout", elapsed)
+	}
+}
+
+func TestFetchRejects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"title":"nope"}`)
+		case "/ftp":
+			http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{"not html", srv.URL + "/json", ErrNotHTML},
+		{"error status", srv.URL + "/missing", nil},
+		{"unsupported scheme", "ftp://example.com/file", nil},
+		{"redirect to unsupported scheme", srv.URL + "/ftp", nil},
+	}
+
+	f := NewFetcher(Options{AllowPrivate: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.Fetch(context.Background(), tt.url)
+			if err == nil {
+				t.Fatal("Fetch() succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Fetch() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxURLLength         = 2000
+)
+
+// parse reads the head of a page. Open Graph values win over the plain
+// title and description, which many pages keep generic.
+func parse(r io.Reader, base *url.URL) entity.Metadata {
+	var (
+		meta                   entity.Metadata
+		title, description     string
+		ogTitle, ogDescription string
+		icon                   string
+		inTitle                bool
+	)
+
+	z := html.NewTokenizer(r)
+	for done := false; !done; {
+		switch z.Next() {
+		case html.ErrorToken:
+			done = true
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				done = true
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Body {
+				done = true
+				break
+			}
+			if tag == atom.Title {
+				inTitle = title == ""
+				break
+			}
+
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = z.TagAttr()
+				attrs[string(key)] = string(value)
+			}
+
+			switch tag {
+			case atom.Meta:
+				key := strings.ToLower(attrs["property"])
+				if key == "" {
+					key = strings.ToLower(attrs["name"])
+				}
+				content := attrs["content"]
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "description":
+					description = content
+				case "og:image", "og:image:url", "og:image:secure_url", "twitter:image":
+					if meta.ImageURL == "" {
+						meta.ImageURL = resolve(base, content)
+					}
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if (rel == "icon" || rel == "apple-touch-icon") && icon == "" {
+						icon = attrs["href"]
+					}
+				}
+			}
+		}
+	}
+
+	meta.Title = clean(first(ogTitle, title), maxTitleLength)
+	meta.Description = clean(first(ogDescription, description), maxDescriptionLength)
+	if icon != "" {
+		meta.FaviconURL = resolve(base, icon)
+	}
+	return meta
+}
+
+// resolve makes href absolute, keeping only http and https URLs.
+func resolve(base *url.URL, href string) string {
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	if s := u.String(); len(s) <= maxURLLength {
+		return s
+	}
+	return ""
+}
+
+// clean collapses whitespace and cuts s to at most max runes.
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package metadata
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	tests := []struct {
+		name string
+		page string
+		want entity.Metadata
+	}{
+		{
+			name: "open graph wins over title and description",
+			page: `<head>
+				<title>Generic site</title>
+				<meta name="description" content="Generic description">
+				<meta property="og:title" content="The post">
+				<meta property="og:description" content="What the post says">
+			</head>`,
+			want: entity.Metadata{Title: "The post", Description: "What the post says"},
+		},
+		{
+			name: "falls back to title and description",
+			page: `<head><title>  Plain
+				title </title><meta name="DESCRIPTION" content="Plain description"></head>`,
+			want: entity.Metadata{Title: "Plain title", Description: "Plain description"},
+		},
+		{
+			name: "empty open graph values do not win",
+			page: `<head><title>Title</title><meta property="og:title" content="  "></head>`,
+			want: entity.Metadata{Title: "Title"},
+		},
+		{
+			name: "relative URLs are resolved",
+			page: `<head>
+				<link rel="shortcut icon" href="../favicon.ico">
+				<meta property="og:image" content="/img/cover.png">
+			</head>`,
+			want: entity.Metadata{
+				FaviconURL: "https://example.com/favicon.ico",
+				ImageURL:   "https://example.com/img/cover.png",
+			},
+		},
+		{
+			name: "first icon and image are kept",
+			page: `<head>
+				<link rel="icon" href="https://cdn.example.com/a.png">
+				<link rel="apple-touch-icon" href="/b.png">
+				<meta property="og:image" content="https://cdn.example.com/first.png">
+				<meta name="twitter:image" content="https://cdn.example.com/second.png">
+			</head>`,
+			want: entity.Metadata{
+				FaviconURL: "https://cdn.example.com/a.png",
+				ImageURL:   "https://cdn.example.com/first.png",
+			},
+		},
+		{
+			name: "non-http URLs are dropped",
+			page: `<head>
+				<link rel="icon" href="javascript:alert(1)">
+				<meta property="og:image" content="data:image/png;base64,AAAA">
+			</head>`,
+			want: entity.Metadata{},
+		},
+		{
+			name: "stops at the body",
+			page: `<head></head><body><title>Not the title</title></body>`,
+			want: entity.Metadata{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parse(strings.NewReader(tt.page), base); got != tt.want {
+				t.Errorf("parse()\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTruncates(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	long := strings.Repeat("é", maxTitleLength+50)
+	page := `<head><title>` + long + `</title><meta name="description" content="` +
+		strings.Repeat("a ", maxDescriptionLength) + `"><link rel="icon" href="/` +
+		strings.Repeat("x", maxURLLength) + `"></head>`
+
+	meta := parse(strings.NewReader(page), base)
+
+	if got := []rune(meta.Title); len(got) != maxTitleLength || got[len(got)-1] != '…' {
+		t.Errorf("Title has %d runes ending in %q, want %d ending in …", len(got), got[len(got)-1], maxTitleLength)
+	}
+	if got := []rune(meta.Description); len(got) > maxDescriptionLength || !strings.HasSuffix(meta.Description, "…") {
+		t.Errorf("Description has %d runes, want at most %d ending in …", len(got), maxDescriptionLength)
+	}
+	if meta.FaviconURL != "" {
+		t.Errorf("FaviconURL kept a %d byte URL", len(meta.FaviconURL))
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"  a\n\tb  ", 10, "a b"},
+		{"abcdef", 6, "abcdef"},
+		{"abcdef", 4, "abc…"},
+		{"ab cdef", 4, "ab…"},
+		{"héllo", 3, "hé…"},
+		{"bad \xff byte", 20, "bad byte"},
+	}
+
+	for _, tt := range tests {
+		if got := clean(tt.in, tt.max); got != tt.want {
+			t.Errorf("clean(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+}
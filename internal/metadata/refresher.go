@@ -0,0 +1,187 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+const (
+	defaultWorkers         = 4
+	defaultQueueSize       = 1000
+	defaultRefreshInterval = time.Hour
+	defaultMaxAge          = 7 * 24 * time.Hour
+	refreshBatchSize       = 100
+)
+
+type RefresherOptions struct {
+	// Workers is how many pages are fetched at once.
+	Workers int
+	// Interval is how often links with stale metadata are looked for.
+	Interval time.Duration
+	// MaxAge is how long fetched metadata is kept before it is fetched again.
+	MaxAge time.Duration
+	Clock  func() time.Time
+}
+
+// Refresher fetches metadata in the background: right after links are
+// created, and again once it is older than MaxAge. Failed fetches are not
+// retried before MaxAge either, so broken pages are not hammered.
+type Refresher struct {
+	store   storage.MetadataStorage
+	fetcher *Fetcher
+	opts    RefresherOptions
+	queue   chan entity.Link
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func NewRefresher(store storage.MetadataStorage, fetcher *Fetcher, opts RefresherOptions) *Refresher {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultRefreshInterval
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = defaultMaxAge
+	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	return &Refresher{
+		store:   store,
+		fetcher: fetcher,
+		opts:    opts,
+		queue:   make(chan entity.Link, defaultQueueSize),
+		pending: make(map[string]bool),
+	}
+}
+
+// Enqueue schedules a fetch for link without blocking. When the queue is
+// full the link is left for the next periodic refresh.
+func (r *Refresher) Enqueue(link entity.Link) {
+	if !r.claim(link.Code) {
+		return
+	}
+
+	select {
+	case r.queue <- link:
+	default:
+		r.release(link.Code)
+		logger.Debug(context.Background(), "Metadata queue full, deferring fetch", slog.String("code", link.Code))
+	}
+}
+
+// Run fetches queued links and periodically queues stale ones until ctx is
+// done.
+func (r *Refresher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range r.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.queueStale(ctx)
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Refresher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case link := <-r.queue:
+			r.refresh(ctx, link)
+			r.release(link.Code)
+		}
+	}
+}
+
+func (r *Refresher) queueStale(ctx context.Context) {
+	links, err := r.store.StaleMetadata(ctx, r.opts.Clock().Add(-r.opts.MaxAge), refreshBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error(ctx, "Failed to list links with stale metadata", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	for _, link := range links {
+		if !r.claim(link.Code) {
+			continue
+		}
+		select {
+		case r.queue <- link:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Refresher) refresh(ctx context.Context, link entity.Link) {
+	meta, err := r.fetcher.Fetch(ctx, link.OriginalURL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Debug(ctx, "Failed to fetch link metadata",
+			slog.String("code", link.Code),
+			slog.String("error", err.Error()),
+		)
+		// Keep what is known and try again once it is stale.
+		meta = entity.Metadata{
+			Description: link.Description,
+			FaviconURL:  link.FaviconURL,
+			ImageURL:    link.ImageURL,
+		}
+	}
+
+	err = r.store.SetMetadata(ctx, link.Code, meta, r.opts.Clock())
+	// The link may have been deleted while its page was fetched.
+	if err != nil && !errors.Is(err, storage.ErrNotFound) && ctx.Err() == nil {
+		logger.Warn(ctx, "Failed to save link metadata",
+			slog.String("code", link.Code),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// claim marks code as queued, reporting false if it already was.
+func (r *Refresher) claim(code string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[code] {
+		return false
+	}
+	r.pending[code] = true
+	return true
+}
+
+func (r *Refresher) release(code string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, code)
+}
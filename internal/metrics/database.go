@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value float64
+}
+
+// DatabaseHandler exposes connection pool statistics in the Prometheus text
+// exposition format.
+func DatabaseHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, databaseMetrics(db.Stats()))
+	}
+}
+
+func databaseMetrics(stats sql.DBStats) []metric {
+	return []metric{
+		{"go_shorty_db_max_open_connections", "Maximum number of open connections to the database.", "gauge", float64(stats.MaxOpenConnections)},
+		{"go_shorty_db_open_connections", "Number of established connections, both in use and idle.", "gauge", float64(stats.OpenConnections)},
+		{"go_shorty_db_in_use_connections", "Number of connections currently in use.", "gauge", float64(stats.InUse)},
+		{"go_shorty_db_idle_connections", "Number of idle connections.", "gauge", float64(stats.Idle)},
+		{"go_shorty_db_wait_count_total", "Total number of connections waited for.", "counter", float64(stats.WaitCount)},
+		{"go_shorty_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", "counter", stats.WaitDuration.Seconds()},
+		{"go_shorty_db_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", "counter", float64(stats.MaxIdleClosed)},
+		{"go_shorty_db_max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.", "counter", float64(stats.MaxIdleTimeClosed)},
+		{"go_shorty_db_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", "counter", float64(stats.MaxLifetimeClosed)},
+	}
+}
+
+func writeMetrics(w io.Writer, metrics []metric) {
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		fmt.Fprintf(w, "%s %g\n", m.name, m.value)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Igorjr19/go-shorty/internal/auth"
+	"github.com/Igorjr19/go-shorty/internal/logger"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+// APIKeyAuth checks the bearer token of each request and records the key
+// in the context. Requests without a token pass through anonymously unless
+// required is set; an invalid token is always rejected.
+func APIKeyAuth(keys storage.APIKeyStorage, required bool) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				if required {
+					unauthorized(w, "API key required")
+					return
+				}
+				next(w, r)
+				return
+			}
+
+			key, err := keys.FindAPIKey(r.Context(), auth.HashKey(token))
+			if errors.Is(err, storage.ErrNotFound) {
+				logger.Warn(r.Context(), "Rejected invalid API key", slog.String("ip", getIP(r)))
+				unauthorized(w, "Invalid API key")
+				return
+			}
+			if err != nil {
+				logger.Error(r.Context(), "Failed to look up API key", slog.String("error", err.Error()))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			next(w, r.WithContext(auth.WithKey(r.Context(), key)))
+		}
+	}
+}
+
+// RequireAdmin lets through only requests APIKeyAuth identified with an
+// admin key.
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Authenticated(r.Context()) {
+			unauthorized(w, "API key required")
+			return
+		}
+		if !auth.IsAdmin(r.Context()) {
+			http.Error(w, "Admin API key required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="go-shorty"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
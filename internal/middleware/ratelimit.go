@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Igorjr19/go-shorty/internal/logger"
@@ -12,13 +15,18 @@ import (
 type RateLimiter interface {
 	Limit(next http.HandlerFunc) http.HandlerFunc
 	AllowRequest(identifier string) bool
+	UpdateLimit(rate int, window time.Duration)
 }
 
 type InMemoryRateLimiter struct {
 	visitors map[string]*visitor
 	mu       sync.RWMutex
-	rate     int
-	window   time.Duration
+	limit    atomic.Pointer[rateLimit]
+}
+
+type rateLimit struct {
+	rate   int
+	window time.Duration
 }
 
 type visitor struct {
@@ -30,15 +38,20 @@ type visitor struct {
 func NewInMemoryRateLimiter(rate int, window time.Duration) RateLimiter {
 	rl := &InMemoryRateLimiter{
 		visitors: make(map[string]*visitor),
-		rate:     rate,
-		window:   window,
 	}
+	rl.UpdateLimit(rate, window)
 
 	go rl.cleanupVisitors()
 
 	return rl
 }
 
+// UpdateLimit swaps the rate and window atomically. Visitors keep their
+// current counters and are measured against the new limit from then on.
+func (rl *InMemoryRateLimiter) UpdateLimit(rate int, window time.Duration) {
+	rl.limit.Store(&rateLimit{rate: rate, window: window})
+}
+
 func (rl *InMemoryRateLimiter) getVisitor(ip string) *visitor {
 	rl.mu.RLock()
 	v, exists := rl.visitors[ip]
@@ -68,10 +81,11 @@ func (rl *InMemoryRateLimiter) cleanupVisitors() {
 	defer ticker.Stop()
 
 	for range ticker.C {
+		limit := rl.limit.Load()
 		rl.mu.Lock()
 		for ip, v := range rl.visitors {
 			v.mu.Lock()
-			if time.Since(v.lastReset) > rl.window*2 {
+			if time.Since(v.lastReset) > limit.window*2 {
 				delete(rl.visitors, ip)
 			}
 			v.mu.Unlock()
@@ -81,16 +95,17 @@ func (rl *InMemoryRateLimiter) cleanupVisitors() {
 }
 
 func (rl *InMemoryRateLimiter) AllowRequest(identifier string) bool {
+	limit := rl.limit.Load()
 	v := rl.getVisitor(identifier)
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	if time.Since(v.lastReset) > rl.window {
+	if time.Since(v.lastReset) > limit.window {
 		v.requests = 0
 		v.lastReset = time.Now()
 	}
 
-	if v.requests >= rl.rate {
+	if v.requests >= limit.rate {
 		return false
 	}
 
@@ -103,7 +118,9 @@ func (rl *InMemoryRateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
 		ip := getIP(r)
 
 		if !rl.AllowRequest(ip) {
-			logger.RateLimitExceeded(r.Context(), ip, rl.rate, rl.window)
+			limit := rl.limit.Load()
+			logger.RateLimitExceeded(r.Context(), ip, limit.rate, limit.window)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limit.window.Seconds()))))
 			http.Error(w, "Rate limit exceeded. Try again later.", http.StatusTooManyRequests)
 			return
 		}
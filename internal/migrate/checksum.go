@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
+)
+
+var ErrChecksumMismatch = errors.New("applied migrations were modified after being applied")
+
+type MigrationIssue struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Detail  string `json:"detail"`
+}
+
+type ValidationReport struct {
+	Dirty      []MigrationIssue `json:"dirty"`
+	Drifted    []MigrationIssue `json:"drifted"`
+	Missing    []MigrationIssue `json:"missing"`
+	OutOfOrder []MigrationIssue `json:"out_of_order"`
+}
+
+func (r ValidationReport) OK() bool {
+	return len(r.Dirty) == 0 && len(r.Drifted) == 0 && len(r.Missing) == 0 && len(r.OutOfOrder) == 0
+}
+
+// checksum covers the up SQL. Go migrations cannot be hashed meaningfully, so
+// only their name is.
+func (mig migration) checksum() string {
+	content := mig.up.sql
+	if content == "" {
+		content = "go:" + mig.name
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// prepare loads migrations and the applied versions, refusing to continue
+// while a version is dirty, or if an applied migration drifted from its file
+// unless the migrator is forced.
+func (m *Migrator) prepare() ([]migration, map[int]bool, error) {
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkDirty(applied); err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.verifyChecksums(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+
+	versions := make(map[int]bool, len(applied))
+	for version := range applied {
+		versions[version] = true
+	}
+	return migrations, versions, nil
+}
+
+func (m *Migrator) verifyChecksums(migrations []migration, applied map[int]appliedMigration) error {
+	var drifted []string
+
+	for _, mig := range migrations {
+		a, ok := applied[mig.version]
+		if !ok {
+			continue
+		}
+
+		checksum := mig.checksum()
+
+		if a.checksum == "" {
+			if m.dryRun {
+				continue
+			}
+			// Rows written before checksums existed trust the file as it is now.
+			if err := m.exec(m.db, "UPDATE schema_migrations SET checksum = $1 WHERE version = $2", checksum, mig.version); err != nil {
+				return fmt.Errorf("failed to record checksum for migration %d (%s): %w", mig.version, mig.name, err)
+			}
+			logger.Info(context.Background(), "Recorded migration checksum", migrationAttrs(mig, "")...)
+			continue
+		}
+
+		if a.checksum != checksum {
+			drifted = append(drifted, fmt.Sprintf("%d (%s)", mig.version, mig.name))
+		}
+	}
+
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	if m.force {
+		logger.Warn(context.Background(), "Continuing despite modified migrations", slog.String("migrations", strings.Join(drifted, ", ")))
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s (use --force to continue anyway)", ErrChecksumMismatch, strings.Join(drifted, ", "))
+}
+
+// Validate compares the migrations on disk with schema_migrations without
+// changing anything.
+func (m *Migrator) Validate() (ValidationReport, error) {
+	var report ValidationReport
+
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		return report, err
+	}
+
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return report, err
+	}
+
+	latest := 0
+	for version := range applied {
+		latest = max(latest, version)
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, mig := range migrations {
+		known[mig.version] = true
+
+		a, ok := applied[mig.version]
+		if !ok {
+			if mig.version < latest {
+				report.OutOfOrder = append(report.OutOfOrder, MigrationIssue{
+					Version: mig.version,
+					Name:    mig.name,
+					Detail:  fmt.Sprintf("pending but older than applied version %d", latest),
+				})
+			}
+			continue
+		}
+
+		if a.checksum != "" && a.checksum != mig.checksum() {
+			report.Drifted = append(report.Drifted, MigrationIssue{
+				Version: mig.version,
+				Name:    mig.name,
+				Detail:  fmt.Sprintf("checksum %s, applied as %s", shortChecksum(mig.checksum()), shortChecksum(a.checksum)),
+			})
+		}
+	}
+
+	for _, mig := range sortedApplied(applied) {
+		if mig.dirty {
+			report.Dirty = append(report.Dirty, MigrationIssue{
+				Version: mig.version,
+				Name:    mig.name,
+				Detail:  "failed part way, resolve by hand and run 'migrate force'",
+			})
+		}
+		if !known[mig.version] {
+			report.Missing = append(report.Missing, MigrationIssue{
+				Version: mig.version,
+				Name:    mig.name,
+				Detail:  "applied but no migration file found",
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func shortChecksum(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
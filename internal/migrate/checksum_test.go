@@ -0,0 +1,122 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestChecksum(t *testing.T) {
+	sql := migration{version: 1, name: "create_links", up: sqlStep("CREATE TABLE links (code TEXT);")}
+	sameSQL := migration{version: 2, name: "renamed", up: sqlStep("CREATE TABLE links (code TEXT);")}
+	editedSQL := migration{version: 1, name: "create_links", up: sqlStep("CREATE TABLE links (code VARCHAR(10));")}
+	goCode := migration{version: 3, name: "backfill"}
+	renamedGo := migration{version: 3, name: "backfill_titles"}
+
+	if got := sql.checksum(); len(got) != 64 {
+		t.Fatalf("checksum() = %q, want a hex SHA-256", got)
+	}
+	if sql.checksum() != sameSQL.checksum() {
+		t.Error("checksum depends on more than the up SQL")
+	}
+	if sql.checksum() == editedSQL.checksum() {
+		t.Error("editing the up SQL did not change the checksum")
+	}
+	if goCode.checksum() == renamedGo.checksum() {
+		t.Error("renaming a Go migration did not change the checksum")
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	first := migration{version: 1, name: "create_links", up: sqlStep("CREATE TABLE links (code TEXT);")}
+	second := migration{version: 2, name: "add_clicks", up: sqlStep("ALTER TABLE links ADD clicks INT;")}
+	migrations := []migration{first, second}
+
+	tests := []struct {
+		name    string
+		applied map[int]appliedMigration
+		force   bool
+		dryRun  bool
+		wantErr bool
+	}{
+		{
+			name: "matching",
+			applied: map[int]appliedMigration{
+				1: {version: 1, checksum: first.checksum()},
+				2: {version: 2, checksum: second.checksum()},
+			},
+		},
+		{
+			name: "pending migrations are ignored",
+			applied: map[int]appliedMigration{
+				1: {version: 1, checksum: first.checksum()},
+			},
+		},
+		{
+			name: "drifted",
+			applied: map[int]appliedMigration{
+				1: {version: 1, checksum: first.checksum()},
+				2: {version: 2, checksum: "0123456789abcdef"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "drifted with force",
+			applied: map[int]appliedMigration{
+				2: {version: 2, checksum: "0123456789abcdef"},
+			},
+			force: true,
+		},
+		{
+			name: "no recorded checksum in a dry run",
+			applied: map[int]appliedMigration{
+				1: {version: 1},
+			},
+			dryRun: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMigrator(nil, fstest.MapFS{})
+			m.SetForce(tt.force)
+			m.SetDryRun(tt.dryRun)
+
+			err := m.verifyChecksums(migrations, tt.applied)
+			if tt.wantErr != errors.Is(err, ErrChecksumMismatch) {
+				t.Fatalf("verifyChecksums() = %v, want mismatch %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadedMigrationsChecksumTheirFiles(t *testing.T) {
+	files := fstest.MapFS{
+		"001_create_links.up.sql":   {Data: []byte("CREATE TABLE links (code TEXT);")},
+		"001_create_links.down.sql": {Data: []byte("DROP TABLE links;")},
+	}
+
+	m := NewMigrator(nil, files)
+	before, err := m.loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files["001_create_links.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE IF EXISTS links;")}
+	afterDown, err := m.loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0].checksum() != afterDown[0].checksum() {
+		t.Error("editing the down SQL changed the checksum")
+	}
+
+	files["001_create_links.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE links (code TEXT PRIMARY KEY);")}
+	afterUp, err := m.loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0].checksum() == afterUp[0].checksum() {
+		t.Error("editing the up SQL did not change the checksum")
+	}
+}
@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes an empty up/down pair to dir. Versions continue the existing
+// numbering, or use the current UTC time as YYYYMMDDHHMMSS when timestamp is
+// set, which avoids clashes between branches.
+func Create(dir, name string, timestamp bool, now time.Time) (string, string, error) {
+	name = strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("migration name must contain letters or digits")
+	}
+
+	var prefix string
+	if timestamp {
+		prefix = now.UTC().Format("20060102150405")
+	} else {
+		latest, err := latestVersionInDir(dir)
+		if err != nil {
+			return "", "", err
+		}
+		prefix = fmt.Sprintf("%03d", latest+1)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	upPath := filepath.Join(dir, prefix+"_"+name+".up.sql")
+	downPath := filepath.Join(dir, prefix+"_"+name+".down.sql")
+
+	for _, path := range []string{upPath, downPath} {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create migration file: %w", err)
+		}
+		file.Close()
+	}
+
+	return upPath, downPath, nil
+}
+
+func latestVersionInDir(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	latest := 0
+	for _, file := range files {
+		prefix, _, found := strings.Cut(file.Name(), "_")
+		if !found || !strings.HasSuffix(file.Name(), ".sql") {
+			continue
+		}
+		if version, err := parseVersion(prefix); err == nil {
+			latest = max(latest, version)
+		}
+	}
+	return latest, nil
+}
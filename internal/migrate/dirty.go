@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
+)
+
+var ErrDirty = errors.New("database is in a dirty migration state")
+
+func checkDirty(applied map[int]appliedMigration) error {
+	for _, a := range sortedApplied(applied) {
+		if a.dirty {
+			return fmt.Errorf("%w: migration %d (%s) failed part way; fix the database by hand, then run 'migrate force <version>'",
+				ErrDirty, a.version, a.name)
+		}
+	}
+	return nil
+}
+
+// Force records exactly the migrations up to and including version as
+// applied and clean, without running any SQL. It is the way out of a dirty
+// state once an operator has repaired the schema by hand.
+func (m *Migrator) Force(version int) error {
+	return m.withLock(func() error { return m.forceVersion(version) })
+}
+
+func (m *Migrator) forceVersion(version int) error {
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && !hasVersion(migrations, version) {
+		return fmt.Errorf("migration %d does not exist", version)
+	}
+
+	if m.dryRun {
+		fmt.Printf("DELETE FROM schema_migrations WHERE version > %d;\n", version)
+		for _, mig := range migrations {
+			if mig.version <= version {
+				fmt.Printf("-- mark %d (%q) as applied and clean\n", mig.version, mig.name)
+			}
+		}
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.ensureMigrationsTable(tx); err != nil {
+		return err
+	}
+
+	if err := m.exec(tx, "DELETE FROM schema_migrations WHERE version > $1", version); err != nil {
+		return fmt.Errorf("failed to update schema_migrations: %w", err)
+	}
+
+	for _, mig := range migrations {
+		if mig.version > version {
+			break
+		}
+		err := m.exec(tx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+			ON CONFLICT (version) DO UPDATE SET dirty = false, name = EXCLUDED.name, checksum = EXCLUDED.checksum`,
+			mig.version, mig.name, mig.checksum())
+		if err != nil {
+			return fmt.Errorf("failed to update schema_migrations: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Info(context.Background(), "Forced schema version", slog.Int("version", version))
+	return nil
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoMigrationsApplied = errors.New("no migrations applied")
+
+// Goto applies or reverts migrations until exactly the migrations up to and
+// including version are applied. Version 0 reverts everything.
+func (m *Migrator) Goto(version int) error {
+	return m.withLock(func() error { return m.gotoVersion(version) })
+}
+
+// Redo reverts the latest applied migration and applies it again.
+func (m *Migrator) Redo() error {
+	return m.withLock(m.redo)
+}
+
+func (m *Migrator) gotoVersion(version int) error {
+	migrations, applied, err := m.prepare()
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && !hasVersion(migrations, version) {
+		return fmt.Errorf("migration %d does not exist", version)
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if mig.version <= version || !applied[mig.version] {
+			continue
+		}
+		if err := m.applyMigration(mig, "down"); err != nil {
+			return err
+		}
+	}
+
+	for _, mig := range migrations {
+		if mig.version > version || applied[mig.version] {
+			continue
+		}
+		if err := m.applyMigration(mig, "up"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) redo() error {
+	migrations, applied, err := m.prepare()
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if !applied[mig.version] {
+			continue
+		}
+
+		if err := m.applyMigration(mig, "down"); err != nil {
+			return err
+		}
+		return m.applyMigration(mig, "up")
+	}
+
+	return ErrNoMigrationsApplied
+}
+
+func hasVersion(migrations []migration, version int) bool {
+	for _, mig := range migrations {
+		if mig.version == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
+)
+
+// advisoryLockKey identifies the migration lock. Every go-shorty migrator
+// against the same database contends for it.
+const advisoryLockKey int64 = 0x676f73686f727479
+
+const (
+	defaultLockTimeout = time.Minute
+	lockPollInterval   = 500 * time.Millisecond
+	// minPoolSize covers the connection holding the lock and the one the
+	// migrations run on.
+	minPoolSize = 2
+)
+
+var (
+	ErrLockTimeout  = errors.New("timed out waiting for the migration lock")
+	ErrPoolTooSmall = errors.New("migrations need at least 2 open database connections")
+)
+
+// SetLockTimeout bounds how long a run waits for another process holding the
+// migration lock. Zero waits indefinitely.
+func (m *Migrator) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+}
+
+// withLock runs fn while holding a session-level advisory lock, so replicas
+// starting together apply each migration exactly once. Dry runs change
+// nothing and skip the lock. The lock keeps its connection for the whole run,
+// so a pool limited to one connection would wait forever for the next.
+func (m *Migrator) withLock(fn func() error) error {
+	if m.dryRun {
+		return fn()
+	}
+
+	if open := m.db.Stats().MaxOpenConnections; open > 0 && open < minPoolSize {
+		return fmt.Errorf("%w, got %d", ErrPoolTooSmall, open)
+	}
+
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reserve a connection for the migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.acquireLock(ctx, conn); err != nil {
+		return err
+	}
+	defer m.releaseLock(conn)
+
+	return fn()
+}
+
+func (m *Migrator) acquireLock(ctx context.Context, conn *sql.Conn) error {
+	if m.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.lockTimeout)
+		defer cancel()
+	}
+
+	waiting := false
+	for {
+		acquired, err := tryLock(ctx, conn)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+
+		if !waiting {
+			logger.Info(ctx, "Waiting for another process to finish migrating", slog.Duration("timeout", m.lockTimeout))
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w after %s", ErrLockTimeout, m.lockTimeout)
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func tryLock(ctx context.Context, conn *sql.Conn) (bool, error) {
+	query := "SELECT pg_try_advisory_lock($1)"
+	start := time.Now()
+
+	var acquired bool
+	err := conn.QueryRowContext(ctx, query, advisoryLockKey).Scan(&acquired)
+	logger.DatabaseQuery(ctx, query, []any{advisoryLockKey}, 1, time.Since(start), err)
+	return acquired, err
+}
+
+func (m *Migrator) releaseLock(conn *sql.Conn) {
+	if err := m.exec(conn, "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+		logger.Warn(context.Background(), "Failed to release migration lock", slog.String("error", err.Error()))
+	}
+}
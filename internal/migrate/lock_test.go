@@ -0,0 +1,28 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRunsRejectSingleConnectionPools(t *testing.T) {
+	// Opening does not connect, and the pool is checked before any query.
+	db, err := sql.Open("postgres", "postgres://localhost:1/none?sslmode=disable&connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	m := NewMigrator(db, fstest.MapFS{})
+	if err := m.Up(); !errors.Is(err, ErrPoolTooSmall) {
+		t.Fatalf("Up() = %v, want %v", err, ErrPoolTooSmall)
+	}
+
+	m.SetDryRun(true)
+	if err := m.Up(); errors.Is(err, ErrPoolTooSmall) {
+		t.Fatalf("dry run Up() = %v, want it to skip the lock", err)
+	}
+}
@@ -1,66 +1,176 @@
 package migrate
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Igorjr19/go-shorty/internal/logger"
 )
 
 type Migrator struct {
-	db             *sql.DB
-	migrationsPath string
+	db          *sql.DB
+	migrations  fs.FS
+	dryRun      bool
+	force       bool
+	lockTimeout time.Duration
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type appliedMigration struct {
+	version   int
+	name      string
+	appliedAt time.Time
+	checksum  string
+	dirty     bool
 }
 
 type migration struct {
 	version int
 	name    string
-	upSQL   string
-	downSQL string
+	up      step
+	down    step
 }
 
-func NewMigrator(db *sql.DB, migrationsPath string) *Migrator {
+// NewMigrator reads migrations from the root of migrations, which is usually
+// the embedded migrations.FS or os.DirFS during development.
+func NewMigrator(db *sql.DB, migrations fs.FS) *Migrator {
 	return &Migrator{
-		db:             db,
-		migrationsPath: migrationsPath,
+		db:          db,
+		migrations:  migrations,
+		lockTimeout: defaultLockTimeout,
 	}
 }
 
-func (m *Migrator) ensureMigrationsTable() error {
-	query := `
-		CREATE TABLE IF NOT EXISTS schema_migrations (
-			version INTEGER PRIMARY KEY,
+// SetDryRun makes Up, Down and their step variants print the SQL they would
+// run instead of executing it.
+func (m *Migrator) SetDryRun(dryRun bool) {
+	m.dryRun = dryRun
+}
+
+// SetForce lets runs continue when applied migrations no longer match their
+// files on disk.
+func (m *Migrator) SetForce(force bool) {
+	m.force = force
+}
+
+// ensureMigrationsTable creates the bookkeeping table and upgrades tables
+// created by older versions of the migrator.
+func (m *Migrator) ensureMigrationsTable(e execer) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
 			name TEXT NOT NULL,
 			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`
-	_, err := m.db.Exec(query)
-	return err
+		)`,
+		`ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS dirty BOOLEAN NOT NULL DEFAULT false`,
+		// Timestamp versions do not fit the INTEGER column older tables used.
+		`DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'schema_migrations'
+					AND column_name = 'version' AND data_type = 'integer'
+			) THEN
+				ALTER TABLE schema_migrations ALTER COLUMN version TYPE BIGINT;
+			END IF;
+		END $$`,
+	}
+
+	for _, query := range statements {
+		if err := m.exec(e, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getAppliedMigrations reads schema_migrations inside a transaction so the
+// table can be created on demand and still be rolled back during a dry run.
+func (m *Migrator) getAppliedMigrations() (map[int]appliedMigration, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.ensureMigrationsTable(tx); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	query := "SELECT version, name, applied_at, checksum, dirty FROM schema_migrations ORDER BY version"
+	start := time.Now()
+
+	applied, err := scanApplied(ctx, tx, query)
+	logger.DatabaseQuery(ctx, query, nil, int64(len(applied)), time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.dryRun {
+		return applied, nil
+	}
+	return applied, tx.Commit()
 }
 
 func (m *Migrator) getAppliedVersions() (map[int]bool, error) {
-	if err := m.ensureMigrationsTable(); err != nil {
+	migrations, err := m.getAppliedMigrations()
+	if err != nil {
 		return nil, err
 	}
 
-	rows, err := m.db.Query("SELECT version FROM schema_migrations ORDER BY version")
+	applied := make(map[int]bool, len(migrations))
+	for version := range migrations {
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+func scanApplied(ctx context.Context, tx *sql.Tx, query string) (map[int]appliedMigration, error) {
+	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	applied := make(map[int]bool)
+	applied := make(map[int]appliedMigration)
 	for rows.Next() {
-		var version int
-		if err := rows.Scan(&version); err != nil {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.appliedAt, &a.checksum, &a.dirty); err != nil {
 			return nil, err
 		}
-		applied[version] = true
+		applied[a.version] = a
 	}
-	return applied, nil
+	return applied, rows.Err()
+}
+
+func (m *Migrator) exec(e execer, query string, args ...any) error {
+	ctx := context.Background()
+	start := time.Now()
+	result, err := e.ExecContext(ctx, query, args...)
+
+	rows := int64(-1)
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			rows = n
+		}
+	}
+
+	logger.DatabaseQuery(ctx, query, args, rows, time.Since(start), err)
+	return err
 }
 
 func parseVersion(versionStr string) (int, error) {
@@ -72,7 +182,9 @@ func parseVersion(versionStr string) (int, error) {
 }
 
 func (m *Migrator) loadMigrations() ([]migration, error) {
-	files, err := os.ReadDir(m.migrationsPath)
+	ctx := context.Background()
+
+	files, err := fs.ReadDir(m.migrations, ".")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
 	}
@@ -96,13 +208,13 @@ func (m *Migrator) loadMigrations() ([]migration, error) {
 		if strings.HasSuffix(name, ".up.sql") {
 			parts := strings.Split(name, "_")
 			if len(parts) < 2 {
-				fmt.Printf("Skipping invalid migration file: %s (expected format: NNN_name.up.sql)\n", name)
+				logger.Warn(ctx, "Skipping invalid migration file", slog.String("file", name), slog.String("expected", "NNN_name.up.sql"))
 				continue
 			}
 
 			version, err = parseVersion(parts[0])
 			if err != nil {
-				fmt.Printf("Skipping file with invalid version: %s (%v)\n", name, err)
+				logger.Warn(ctx, "Skipping file with invalid version", slog.String("file", name), slog.String("error", err.Error()))
 				continue
 			}
 
@@ -112,13 +224,13 @@ func (m *Migrator) loadMigrations() ([]migration, error) {
 		} else if strings.HasSuffix(name, ".down.sql") {
 			parts := strings.Split(name, "_")
 			if len(parts) < 2 {
-				fmt.Printf("Skipping invalid migration file: %s (expected format: NNN_name.down.sql)\n", name)
+				logger.Warn(ctx, "Skipping invalid migration file", slog.String("file", name), slog.String("expected", "NNN_name.down.sql"))
 				continue
 			}
 
 			version, err = parseVersion(parts[0])
 			if err != nil {
-				fmt.Printf("Skipping file with invalid version: %s (%v)\n", name, err)
+				logger.Warn(ctx, "Skipping file with invalid version", slog.String("file", name), slog.String("error", err.Error()))
 				continue
 			}
 
@@ -129,7 +241,7 @@ func (m *Migrator) loadMigrations() ([]migration, error) {
 			continue
 		}
 
-		content, err := os.ReadFile(filepath.Join(m.migrationsPath, name))
+		content, err := fs.ReadFile(m.migrations, name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read migration file %s: %w", name, err)
 		}
@@ -142,14 +254,21 @@ func (m *Migrator) loadMigrations() ([]migration, error) {
 		}
 
 		if direction == "up" {
-			migrationsMap[version].upSQL = string(content)
-			fmt.Printf("Loaded migration %d (%s) up: %d bytes\n", version, migrationName, len(content))
+			migrationsMap[version].up = sqlStep(string(content))
+			logger.Debug(ctx, "Loaded migration", loadedAttrs(version, migrationName, "up", len(content))...)
 		} else {
-			migrationsMap[version].downSQL = string(content)
-			fmt.Printf("Loaded migration %d (%s) down: %d bytes\n", version, migrationName, len(content))
+			migrationsMap[version].down = sqlStep(string(content))
+			logger.Debug(ctx, "Loaded migration", loadedAttrs(version, migrationName, "down", len(content))...)
 		}
 	}
 
+	for version, mig := range registered {
+		if migrationsMap[version] != nil {
+			return nil, fmt.Errorf("migration %d is defined both as SQL files and as Go code", version)
+		}
+		migrationsMap[version] = &mig
+	}
+
 	migrations := make([]migration, 0, len(migrationsMap))
 	for _, mig := range migrationsMap {
 		migrations = append(migrations, *mig)
@@ -163,19 +282,30 @@ func (m *Migrator) loadMigrations() ([]migration, error) {
 }
 
 func (m *Migrator) Up() error {
-	migrations, err := m.loadMigrations()
-	if err != nil {
-		return err
-	}
+	return m.withLock(m.up)
+}
+
+func (m *Migrator) UpSteps(steps int) error {
+	return m.withLock(func() error { return m.upSteps(steps) })
+}
+
+func (m *Migrator) Down() error {
+	return m.withLock(m.down)
+}
 
-	applied, err := m.getAppliedVersions()
+func (m *Migrator) DownSteps(steps int) error {
+	return m.withLock(func() error { return m.downSteps(steps) })
+}
+
+func (m *Migrator) up() error {
+	migrations, applied, err := m.prepare()
 	if err != nil {
 		return err
 	}
 
 	for _, mig := range migrations {
 		if applied[mig.version] {
-			fmt.Printf("Migration %d (%s) already applied, skipping\n", mig.version, mig.name)
+			logger.Debug(context.Background(), "Migration already applied, skipping", migrationAttrs(mig, "up")...)
 			continue
 		}
 
@@ -187,13 +317,8 @@ func (m *Migrator) Up() error {
 	return nil
 }
 
-func (m *Migrator) UpSteps(steps int) error {
-	migrations, err := m.loadMigrations()
-	if err != nil {
-		return err
-	}
-
-	applied, err := m.getAppliedVersions()
+func (m *Migrator) upSteps(steps int) error {
+	migrations, applied, err := m.prepare()
 	if err != nil {
 		return err
 	}
@@ -217,13 +342,8 @@ func (m *Migrator) UpSteps(steps int) error {
 	return nil
 }
 
-func (m *Migrator) Down() error {
-	migrations, err := m.loadMigrations()
-	if err != nil {
-		return err
-	}
-
-	applied, err := m.getAppliedVersions()
+func (m *Migrator) down() error {
+	migrations, applied, err := m.prepare()
 	if err != nil {
 		return err
 	}
@@ -231,7 +351,7 @@ func (m *Migrator) Down() error {
 	for i := len(migrations) - 1; i >= 0; i-- {
 		mig := migrations[i]
 		if !applied[mig.version] {
-			fmt.Printf("Migration %d (%s) not applied, skipping\n", mig.version, mig.name)
+			logger.Debug(context.Background(), "Migration not applied, skipping", migrationAttrs(mig, "down")...)
 			continue
 		}
 
@@ -243,13 +363,8 @@ func (m *Migrator) Down() error {
 	return nil
 }
 
-func (m *Migrator) DownSteps(steps int) error {
-	migrations, err := m.loadMigrations()
-	if err != nil {
-		return err
-	}
-
-	applied, err := m.getAppliedVersions()
+func (m *Migrator) downSteps(steps int) error {
+	migrations, applied, err := m.prepare()
 	if err != nil {
 		return err
 	}
@@ -275,43 +390,163 @@ func (m *Migrator) DownSteps(steps int) error {
 }
 
 func (m *Migrator) applyMigration(mig migration, direction string) error {
-	var sql string
-	if direction == "up" {
-		sql = mig.upSQL
+	s := mig.up
+	if direction == "down" {
+		s = mig.down
+	}
+
+	if s.empty() {
+		return fmt.Errorf("no %s migration found for %d (%s)", direction, mig.version, mig.name)
+	}
+
+	if m.dryRun {
+		printStep(mig, direction, s)
+		return nil
+	}
+
+	ctx := context.Background()
+	attrs := migrationAttrs(mig, direction)
+	attrs = append(attrs, slog.Bool("transactional", !s.noTx))
+	logger.Info(ctx, "Running migration", attrs...)
+
+	start := time.Now()
+	var err error
+	if s.noTx {
+		err = m.runWithoutTransaction(mig, direction, s)
 	} else {
-		sql = mig.downSQL
+		err = m.runInTransaction(mig, direction, s)
 	}
 
-	if sql == "" {
-		return fmt.Errorf("no %s SQL found for migration %d (%s)", direction, mig.version, mig.name)
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	if err != nil {
+		logger.Error(ctx, "Migration failed", append(attrs, slog.String("error", err.Error()))...)
+		return err
 	}
 
+	logger.Info(ctx, "Migration completed", attrs...)
+	return nil
+}
+
+func (m *Migrator) runInTransaction(mig migration, direction string, s step) error {
 	tx, err := m.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	fmt.Printf("Running migration %d (%s) %s...\n", mig.version, mig.name, direction)
-
-	if _, err := tx.Exec(sql); err != nil {
+	if s.txFunc != nil {
+		err = s.txFunc(context.Background(), tx)
+	} else {
+		err = m.exec(tx, s.sql)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to execute migration %d (%s) %s: %w", mig.version, mig.name, direction, err)
 	}
 
+	if err := m.record(tx, mig, direction); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// runWithoutTransaction executes statements one by one, which statements
+// such as CREATE INDEX CONCURRENTLY require. The version is marked dirty
+// first, so a failure half way leaves a record an operator must resolve.
+func (m *Migrator) runWithoutTransaction(mig migration, direction string, s step) error {
+	if err := m.markDirty(mig, direction); err != nil {
+		return err
+	}
+
+	if s.dbFunc != nil {
+		if err := s.dbFunc(context.Background(), m.db); err != nil {
+			return m.dirtyError(mig, direction, err)
+		}
+	} else {
+		for _, statement := range splitStatements(s.sql) {
+			if err := m.exec(m.db, statement); err != nil {
+				return m.dirtyError(mig, direction, err)
+			}
+		}
+	}
+
+	return m.record(m.db, mig, direction)
+}
+
+func (m *Migrator) markDirty(mig migration, direction string) error {
+	var err error
 	if direction == "up" {
-		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.version, mig.name)
+		err = m.exec(m.db, `INSERT INTO schema_migrations (version, name, checksum, dirty) VALUES ($1, $2, $3, true)
+			ON CONFLICT (version) DO UPDATE SET dirty = true`, mig.version, mig.name, mig.checksum())
 	} else {
-		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", mig.version)
+		err = m.exec(m.db, "UPDATE schema_migrations SET dirty = true WHERE version = $1", mig.version)
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to update schema_migrations: %w", err)
+		return fmt.Errorf("failed to mark migration %d as dirty: %w", mig.version, err)
 	}
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+func (m *Migrator) dirtyError(mig migration, direction string, err error) error {
+	return fmt.Errorf("failed to execute migration %d (%s) %s, version left dirty: %w", mig.version, mig.name, direction, err)
+}
+
+func (m *Migrator) record(e execer, mig migration, direction string) error {
+	var err error
+	if direction == "up" {
+		err = m.exec(e, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+			ON CONFLICT (version) DO UPDATE SET dirty = false, applied_at = CURRENT_TIMESTAMP`, mig.version, mig.name, mig.checksum())
+	} else {
+		err = m.exec(e, "DELETE FROM schema_migrations WHERE version = $1", mig.version)
 	}
 
-	fmt.Printf("Migration %d (%s) %s completed successfully!\n", mig.version, mig.name, direction)
+	if err != nil {
+		return fmt.Errorf("failed to update schema_migrations: %w", err)
+	}
 	return nil
 }
+
+func migrationAttrs(mig migration, direction string) []any {
+	attrs := []any{
+		slog.Int("version", mig.version),
+		slog.String("name", mig.name),
+	}
+	if direction != "" {
+		attrs = append(attrs, slog.String("direction", direction))
+	}
+	return attrs
+}
+
+func loadedAttrs(version int, name, direction string, size int) []any {
+	return []any{
+		slog.Int("version", version),
+		slog.String("name", name),
+		slog.String("direction", direction),
+		slog.Int("bytes", size),
+	}
+}
+
+// printStep writes what a dry run would execute. It is the command's output
+// rather than a log line, so it goes straight to stdout. The name is quoted
+// everywhere it appears so it cannot end a comment or a string literal.
+func printStep(mig migration, direction string, s step) {
+	switch {
+	case s.sql == "":
+		fmt.Printf("-- Migration %d (%q) %s runs Go code\n", mig.version, mig.name, direction)
+	case s.noTx:
+		fmt.Printf("-- Migration %d (%q) %s, outside a transaction\n%s\n", mig.version, mig.name, direction, strings.TrimSpace(s.sql))
+	default:
+		fmt.Printf("-- Migration %d (%q) %s\n%s\n", mig.version, mig.name, direction, strings.TrimSpace(s.sql))
+	}
+
+	if direction == "up" {
+		fmt.Printf("INSERT INTO schema_migrations (version, name, checksum) VALUES (%d, %s, %s);\n\n",
+			mig.version, pq.QuoteLiteral(mig.name), pq.QuoteLiteral(mig.checksum()))
+	} else {
+		fmt.Printf("DELETE FROM schema_migrations WHERE version = %d;\n\n", mig.version)
+	}
+}
@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	StateApplied = "applied"
+	StatePending = "pending"
+	StateMissing = "missing"
+	StateDirty   = "dirty"
+)
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// Status lists every migration known either from the migrations directory or
+// from schema_migrations. Versions recorded in the database without a file
+// are reported as missing.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[int]bool, len(migrations))
+
+	for _, mig := range migrations {
+		known[mig.version] = true
+
+		status := MigrationStatus{
+			Version: mig.version,
+			Name:    mig.name,
+			State:   StatePending,
+		}
+		if a, ok := applied[mig.version]; ok {
+			appliedAt := a.appliedAt
+			status.State = StateApplied
+			status.AppliedAt = &appliedAt
+			if a.dirty {
+				status.State = StateDirty
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, a := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := a.appliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      a.name,
+			State:     StateMissing,
+			AppliedAt: &appliedAt,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Version returns the highest applied version, or 0 when nothing is applied.
+func (m *Migrator) Version() (int, error) {
+	applied, err := m.getAppliedVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+func sortedApplied(applied map[int]appliedMigration) []appliedMigration {
+	sorted := make([]appliedMigration, 0, len(applied))
+	for _, a := range applied {
+		sorted = append(sorted, a)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].version < sorted[j].version
+	})
+	return sorted
+}
@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// NoTransactionDirective, placed in the leading comments of a migration file,
+// runs that file outside a transaction, one statement at a time.
+const NoTransactionDirective = "-- migrate:no-transaction"
+
+type TxFunc func(ctx context.Context, tx *sql.Tx) error
+
+type DBFunc func(ctx context.Context, db *sql.DB) error
+
+type step struct {
+	sql    string
+	noTx   bool
+	txFunc TxFunc
+	dbFunc DBFunc
+}
+
+var registered = make(map[int]migration)
+
+// Register adds a Go migration that runs inside a transaction. It is meant to
+// be called from init functions and panics on duplicate versions.
+func Register(version int, name string, up, down TxFunc) {
+	register(version, name, step{txFunc: up}, step{txFunc: down})
+}
+
+// RegisterNoTx adds a Go migration that receives the database handle itself,
+// for work that cannot run in a single transaction such as batched backfills.
+func RegisterNoTx(version int, name string, up, down DBFunc) {
+	register(version, name, step{noTx: true, dbFunc: up}, step{noTx: true, dbFunc: down})
+}
+
+func register(version int, name string, up, down step) {
+	if _, exists := registered[version]; exists {
+		panic(fmt.Sprintf("migrate: migration %d registered twice", version))
+	}
+	registered[version] = migration{
+		version: version,
+		name:    name,
+		up:      up,
+		down:    down,
+	}
+}
+
+func sqlStep(content string) step {
+	return step{
+		sql:  content,
+		noTx: hasNoTransactionDirective(content),
+	}
+}
+
+func (s step) empty() bool {
+	return strings.TrimSpace(s.sql) == "" && s.txFunc == nil && s.dbFunc == nil
+}
+
+func hasNoTransactionDirective(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			return false
+		}
+		if line == NoTransactionDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits SQL on semicolons that are not inside quotes,
+// dollar-quoted bodies or comments.
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" && !onlyComments(statement) {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(content) {
+				if content[end] == c {
+					if end+1 < len(content) && content[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			current.WriteString(content[i:min(end+1, len(content))])
+			i = end
+		case c == '-' && strings.HasPrefix(content[i:], "--"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			current.WriteString(content[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(content)
+			} else {
+				end = i + 2 + end + 2
+			}
+			current.WriteString(content[i:end])
+			i = end - 1
+		case c == '$':
+			tag := dollarTag(content[i:])
+			if tag == "" {
+				current.WriteByte(c)
+				continue
+			}
+			end := strings.Index(content[i+len(tag):], tag)
+			if end < 0 {
+				end = len(content)
+			} else {
+				end = i + len(tag) + end + len(tag)
+			}
+			current.WriteString(content[i:end])
+			i = end - 1
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// dollarTag returns the opening tag ($$ or $name$) at the start of s.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[:i+1]
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func onlyComments(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
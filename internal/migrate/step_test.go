@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "single statement without semicolon",
+			sql:  "CREATE INDEX idx ON links(code)",
+			want: []string{"CREATE INDEX idx ON links(code)"},
+		},
+		{
+			name: "several statements",
+			sql:  "CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\n",
+			want: []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name: "semicolon in a string",
+			sql:  "INSERT INTO t VALUES ('a;b');INSERT INTO t VALUES ('it''s; fine');",
+			want: []string{"INSERT INTO t VALUES ('a;b')", "INSERT INTO t VALUES ('it''s; fine')"},
+		},
+		{
+			name: "semicolon in a quoted identifier",
+			sql:  `SELECT 1 AS "a;b"; SELECT 2`,
+			want: []string{`SELECT 1 AS "a;b"`, "SELECT 2"},
+		},
+		{
+			name: "semicolon in comments",
+			sql:  "-- first; still a comment\nSELECT 1; /* block; comment */ SELECT 2;",
+			want: []string{"-- first; still a comment\nSELECT 1", "/* block; comment */ SELECT 2"},
+		},
+		{
+			name: "dollar-quoted body",
+			sql:  "CREATE FUNCTION f() RETURNS INT AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;\nSELECT f();",
+			want: []string{"CREATE FUNCTION f() RETURNS INT AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			name: "tagged dollar quote containing $$",
+			sql:  "DO $body$ BEGIN PERFORM '$$;'; END $body$; SELECT 1;",
+			want: []string{"DO $body$ BEGIN PERFORM '$$;'; END $body$", "SELECT 1"},
+		},
+		{
+			name: "positional parameter is not a dollar quote",
+			sql:  "PREPARE p AS SELECT $1; EXECUTE p(1);",
+			want: []string{"PREPARE p AS SELECT $1", "EXECUTE p(1)"},
+		},
+		{
+			name: "comment-only chunks are dropped",
+			sql:  "-- migrate:no-transaction\n-- builds the index online\n;\nCREATE INDEX CONCURRENTLY idx ON links(owner);\n-- trailing note\n",
+			want: []string{"CREATE INDEX CONCURRENTLY idx ON links(owner)"},
+		},
+		{
+			name: "unterminated string runs to the end",
+			sql:  "SELECT 'oops; SELECT 2",
+			want: []string{"SELECT 'oops; SELECT 2"},
+		},
+		{
+			name: "empty",
+			sql:  " \n;;\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.sql)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q)\n got %q\nwant %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasNoTransactionDirective(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want bool
+	}{
+		{"first line", "-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx ON links(owner);", true},
+		{"after other comments", "-- add an index\n\n  -- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx ON links(owner);", true},
+		{"after a statement", "SELECT 1;\n-- migrate:no-transaction\n", false},
+		{"absent", "-- add an index\nCREATE INDEX idx ON links(owner);", false},
+		{"not the whole comment", "-- migrate:no-transaction please\nSELECT 1;", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasNoTransactionDirective(tt.sql); got != tt.want {
+				t.Errorf("hasNoTransactionDirective() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package qrcode renders QR codes for short links as PNG or SVG.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+const (
+	DefaultSize   = 256
+	DefaultMargin = 4
+	MinSize       = 64
+	MaxSize       = 2048
+	MaxMargin     = 32
+)
+
+var (
+	ErrUnknownFormat = errors.New("format must be png or svg")
+	ErrInvalidLevel  = errors.New("level must be L, M, Q or H")
+	ErrInvalidColor  = errors.New("colors must be hex, e.g. 000000 or #fff")
+	ErrInvalidSize   = fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	ErrInvalidMargin = fmt.Errorf("margin must be between 0 and %d modules", MaxMargin)
+)
+
+// Options control how a code is drawn. Size is the width of the image in
+// pixels and Margin the quiet zone around the code, in modules.
+type Options struct {
+	Size       int
+	Margin     int
+	Level      qr.Level
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// DefaultOptions draws black on white with medium error correction.
+func DefaultOptions() Options {
+	return Options{
+		Size:       DefaultSize,
+		Margin:     DefaultMargin,
+		Level:      qr.M,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return ErrInvalidSize
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return ErrInvalidMargin
+	}
+	return nil
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case PNG, SVG:
+		return f, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// ParseLevel reads an error-correction level: L (7%), M (15%), Q (25%) or
+// H (30%) of the code can be damaged and still be read.
+func ParseLevel(s string) (qr.Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return qr.L, nil
+	case "M":
+		return qr.M, nil
+	case "Q":
+		return qr.Q, nil
+	case "H":
+		return qr.H, nil
+	default:
+		return 0, ErrInvalidLevel
+	}
+}
+
+// ParseColor reads a color as 3, 4, 6 or 8 hex digits, with or without a
+// leading '#'; the optional last digits are the alpha.
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 || len(s) == 4 {
+		var b strings.Builder
+		for _, c := range s {
+			b.WriteRune(c)
+			b.WriteRune(c)
+		}
+		s = b.String()
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// Encode writes a QR code for content to w.
+func Encode(w io.Writer, content string, format Format, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	code, err := qr.Encode(content, opts.Level)
+	if err != nil {
+		return err
+	}
+
+	if format == SVG {
+		return writeSVG(w, code, opts)
+	}
+	return writePNG(w, code, opts)
+}
+
+// writePNG scales modules by whole pixels so they stay sharp, which can leave
+// the image slightly smaller than the requested size.
+func writePNG(w io.Writer, code *qr.Code, opts Options) error {
+	modules := code.Size + 2*opts.Margin
+	scale := max(opts.Size/modules, 1)
+	side := modules * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{opts.Background, opts.Foreground})
+	for y := range code.Size {
+		for x := range code.Size {
+			if !code.Black(x, y) {
+				continue
+			}
+			px, py := (x+opts.Margin)*scale, (y+opts.Margin)*scale
+			for dy := range scale {
+				for dx := range scale {
+					img.SetColorIndex(px+dx, py+dy, 1)
+				}
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// writeSVG draws each row of dark modules as runs in a single path, which
+// keeps the document small.
+func writeSVG(w io.Writer, code *qr.Code, opts Options) error {
+	modules := code.Size + 2*opts.Margin
+
+	var path strings.Builder
+	for y := range code.Size {
+		for x := 0; x < code.Size; {
+			if !code.Black(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < code.Size && code.Black(x, y) {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="%s"%s/><path d="%s" fill="%s"%s/></svg>`+"\n",
+		opts.Size, opts.Size, modules, modules,
+		hex(opts.Background), opacity(opts.Background), path.String(), hex(opts.Foreground), opacity(opts.Foreground))
+	return err
+}
+
+func hex(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func opacity(c color.NRGBA) string {
+	if c.A == 0xff {
+		return ""
+	}
+	return fmt.Sprintf(` fill-opacity="%.3g"`, float64(c.A)/0xff)
+}
@@ -0,0 +1,51 @@
+package shortener
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Policy holds the rules that can be swapped while the service is running.
+type Policy struct {
+	BlockedDomains  []string
+	ReservedAliases []string
+}
+
+type compiledPolicy struct {
+	blockedDomains  []string
+	reservedAliases map[string]bool
+}
+
+func compilePolicy(p Policy) *compiledPolicy {
+	compiled := &compiledPolicy{
+		reservedAliases: make(map[string]bool, len(p.ReservedAliases)),
+	}
+
+	for _, domain := range p.BlockedDomains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			compiled.blockedDomains = append(compiled.blockedDomains, domain)
+		}
+	}
+
+	for _, alias := range p.ReservedAliases {
+		compiled.reservedAliases[strings.ToLower(alias)] = true
+	}
+
+	return compiled
+}
+
+// isBlocked matches the host itself and any of its subdomains.
+func (p *compiledPolicy) isBlocked(u *url.URL) bool {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, domain := range p.blockedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *compiledPolicy) isReserved(code string) bool {
+	return p.reservedAliases[strings.ToLower(code)]
+}
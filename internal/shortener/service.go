@@ -1,52 +1,414 @@
 package shortener
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/rand"
+	"net/url"
+	"regexp"
+	"sync/atomic"
 	"time"
 
 	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/logger"
 	"github.com/Igorjr19/go-shorty/internal/storage"
 )
 
+const (
+	defaultCodeLength = 6
+	maxCodeAttempts   = 5
+)
+
+var (
+	ErrInvalidURL    = errors.New("invalid URL")
+	ErrBlockedDomain = errors.New("URL domain is blocked")
+	ErrInvalidAlias  = errors.New("alias must be 3 to 50 letters, digits, '-' or '_'")
+	ErrReservedAlias = errors.New("alias is reserved")
+	ErrAliasTaken    = errors.New("alias is already in use")
+	ErrRejected      = errors.New("URL rejected")
+	ErrInvalidExpiry = errors.New("expiry must be in the future")
+	ErrLinkDisabled  = errors.New("link is disabled")
+	ErrLinkExpired   = errors.New("link has expired")
+
+	ErrInvalidRedirectType    = errors.New("redirect type must be 301, 302, 307 or 308")
+	ErrInvalidQueryForwarding = errors.New("query forwarding must be prefer_request or prefer_link")
+)
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,50}$`)
+
+// CodeGenerator returns a candidate code of the given length. Candidates that
+// are reserved or already taken are discarded and a new one is requested.
+type CodeGenerator func(length int) string
+
+// Validator runs after the built-in URL and alias checks. A non-nil error
+// rejects the request and is reported wrapped in ErrRejected.
+type Validator func(ctx context.Context, req Request) error
+
+type Options struct {
+	CodeLength   int
+	WriteTimeout time.Duration
+	ReadTimeout  time.Duration
+	Policy       Policy
+	Generator    CodeGenerator
+	Validator    Validator
+	Clock        func() time.Time
+	// OnCreate is called with every link Shorten and ShortenBatch save, e.g.
+	// to fetch its metadata. It must not block.
+	OnCreate func(entity.Link)
+}
+
+type Request struct {
+	URL       string
+	Alias     string
+	Owner     string
+	ExpiresAt *time.Time
+	// RedirectType is the status to redirect with, zero for the default.
+	RedirectType int
+	ForwardPath  bool
+	ForwardQuery entity.QueryForwarding
+}
+
 type Service struct {
 	storage storage.Storage
+	opts    Options
+	policy  atomic.Pointer[compiledPolicy]
 }
 
-func NewService(storage storage.Storage) *Service {
-	return &Service{
+func NewService(storage storage.Storage, opts Options) *Service {
+	if opts.CodeLength <= 0 {
+		opts.CodeLength = defaultCodeLength
+	}
+	if opts.Generator == nil {
+		opts.Generator = RandomCode
+	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	s := &Service{
 		storage: storage,
+		opts:    opts,
+	}
+	s.SetPolicy(opts.Policy)
+	return s
+}
+
+// SetPolicy replaces the blocklist and reserved aliases. Requests already in
+// flight keep the policy they started with.
+func (s *Service) SetPolicy(p Policy) {
+	s.policy.Store(compilePolicy(p))
+}
+
+func (s *Service) Shorten(ctx context.Context, req Request) (entity.Link, error) {
+	policy := s.policy.Load()
+	now := s.opts.Clock()
+
+	ctx, cancel := withTimeout(ctx, s.opts.WriteTimeout)
+	defer cancel()
+
+	if err := s.check(ctx, policy, req, now); err != nil {
+		return entity.Link{}, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		link, ok := s.newLink(policy, req, now)
+
+		err := storage.ErrConflict
+		if ok {
+			err = s.storage.Save(ctx, link)
+		}
+
+		if err == nil {
+			s.created(link)
+			return link, nil
+		}
+
+		if !errors.Is(err, storage.ErrConflict) {
+			return entity.Link{}, err
+		}
+		if req.Alias != "" {
+			return entity.Link{}, ErrAliasTaken
+		}
+		if attempt >= maxCodeAttempts {
+			return entity.Link{}, err
+		}
+	}
+}
+
+// BatchResult is the outcome of one request of a batch.
+type BatchResult struct {
+	Link entity.Link
+	Err  error
+}
+
+// ShortenBatch checks every request on its own and saves the valid ones
+// together, returning one result per request in the same order. Only a
+// storage failure fails the batch as a whole.
+func (s *Service) ShortenBatch(ctx context.Context, reqs []Request) ([]BatchResult, error) {
+	policy := s.policy.Load()
+	now := s.opts.Clock()
+
+	ctx, cancel := withTimeout(ctx, s.opts.WriteTimeout)
+	defer cancel()
+
+	results := make([]BatchResult, len(reqs))
+	var pending []int
+	for i, req := range reqs {
+		if err := s.check(ctx, policy, req, now); err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		var links []entity.Link
+		var sent, retry []int
+		for _, i := range pending {
+			link, ok := s.newLink(policy, reqs[i], now)
+			if !ok {
+				retry = append(retry, i)
+				continue
+			}
+			links = append(links, link)
+			sent = append(sent, i)
+		}
+
+		errs, err := s.storage.SaveBatch(ctx, links)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, i := range sent {
+			switch {
+			case errs[j] == nil:
+				results[i].Link = links[j]
+				s.created(links[j])
+			case reqs[i].Alias != "":
+				results[i].Err = ErrAliasTaken
+			default:
+				retry = append(retry, i)
+			}
+		}
+
+		if attempt >= maxCodeAttempts {
+			for _, i := range retry {
+				results[i].Err = storage.ErrConflict
+			}
+			break
+		}
+		pending = retry
+	}
+
+	return results, nil
+}
+
+func (s *Service) check(ctx context.Context, policy *compiledPolicy, req Request, now time.Time) error {
+	if err := checkURL(policy, req.URL); err != nil {
+		return err
+	}
+
+	if req.Alias != "" {
+		if !aliasPattern.MatchString(req.Alias) {
+			return ErrInvalidAlias
+		}
+		if policy.isReserved(req.Alias) {
+			return ErrReservedAlias
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return ErrInvalidExpiry
+	}
+
+	if !entity.ValidRedirectType(req.RedirectType) {
+		return ErrInvalidRedirectType
+	}
+	if !req.ForwardQuery.Valid() {
+		return ErrInvalidQueryForwarding
+	}
+
+	if s.opts.Validator != nil {
+		if err := s.opts.Validator(ctx, req); err != nil {
+			return fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+	}
+	return nil
+}
+
+// checkURL accepts absolute http and https URLs outside blocked domains.
+func checkURL(policy *compiledPolicy, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+
+	if policy.isBlocked(u) {
+		return ErrBlockedDomain
+	}
+	return nil
+}
+
+// newLink builds the link for req, generating a code unless an alias was
+// given. It reports false when the generated code cannot be used.
+func (s *Service) newLink(policy *compiledPolicy, req Request, now time.Time) (entity.Link, bool) {
+	link := entity.Link{
+		Code:         req.Alias,
+		OriginalURL:  req.URL,
+		CreatedAt:    now,
+		Owner:        req.Owner,
+		ExpiresAt:    req.ExpiresAt,
+		RedirectType: req.RedirectType,
+		ForwardPath:  req.ForwardPath,
+		ForwardQuery: req.ForwardQuery,
 	}
+
+	if req.Alias == "" {
+		link.Code = s.opts.Generator(s.opts.CodeLength)
+		if link.Code == "" || policy.isReserved(link.Code) {
+			return entity.Link{}, false
+		}
+	}
+	return link, true
 }
 
-func (s *Service) Shorten(url string) (string, error) {
-	const codeLength = 6
+func (s *Service) created(link entity.Link) {
+	if s.opts.OnCreate != nil {
+		s.opts.OnCreate(link)
+	}
+}
+
+// RandomCode is the default CodeGenerator, drawing from letters and digits.
+func RandomCode(length int) string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-	codeBytes := make([]byte, codeLength)
+	codeBytes := make([]byte, length)
 	for i := range codeBytes {
 		codeBytes[i] = letters[rand.Intn(len(letters))]
 	}
-	code := string(codeBytes)
+	return string(codeBytes)
+}
 
-	link := entity.Link{
-		Code:        code,
-		OriginalURL: url,
-		CreatedAt:   time.Now(),
+// Resolve returns the destination for code and counts the visit. A failure
+// to count does not prevent the redirect.
+func (s *Service) Resolve(ctx context.Context, code string) (string, error) {
+	link, err := s.Follow(ctx, code)
+	if err != nil {
+		return "", err
 	}
+	return link.OriginalURL, nil
+}
 
-	if err := s.storage.Save(link); err != nil {
-		return "", err
+// Follow is Resolve returning the whole link, for callers that need more
+// than the destination to redirect.
+func (s *Service) Follow(ctx context.Context, code string) (entity.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.ReadTimeout)
+	defer cancel()
+
+	link, err := s.load(ctx, code)
+	if err != nil {
+		return entity.Link{}, err
 	}
 
-	return code, nil
+	if err := s.storage.RecordClick(ctx, code, s.opts.Clock()); err != nil {
+		logger.Warn(ctx, "Failed to record click",
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return link, nil
 }
 
-func (s *Service) Resolve(code string) (string, error) {
-	link, err := s.storage.Load(code)
+// Preview returns the link Resolve would follow, without counting a visit.
+func (s *Service) Preview(ctx context.Context, code string) (entity.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.ReadTimeout)
+	defer cancel()
+
+	return s.load(ctx, code)
+}
 
+// load returns the link behind code if it can still be followed.
+func (s *Service) load(ctx context.Context, code string) (entity.Link, error) {
+	link, err := s.storage.Load(ctx, code)
 	if err != nil {
-		return "", err
+		return entity.Link{}, err
 	}
 
-	return link.OriginalURL, nil
+	if link.DisabledAt != nil {
+		return entity.Link{}, ErrLinkDisabled
+	}
+	if link.Expired(s.opts.Clock()) {
+		return entity.Link{}, ErrLinkExpired
+	}
+	return link, nil
+}
+
+func (s *Service) Get(ctx context.Context, code string) (entity.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.ReadTimeout)
+	defer cancel()
+
+	return s.storage.Load(ctx, code)
+}
+
+func (s *Service) List(ctx context.Context, opts storage.ListOptions) ([]entity.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.ReadTimeout)
+	defer cancel()
+
+	return s.storage.List(ctx, opts)
+}
+
+// Export streams every link matching opts to fn. It is not bounded by the
+// read timeout, since an export takes as long as there are links.
+func (s *Service) Export(ctx context.Context, opts storage.ListOptions, fn func(entity.Link) error) error {
+	return s.storage.Each(ctx, opts, fn)
+}
+
+// Validate checks a link that is restored rather than created. Its code,
+// timestamps and counters are kept as they are, but the destination must
+// still pass the URL check and blocklist, and the code must not be reserved.
+func (s *Service) Validate(link entity.Link) error {
+	policy := s.policy.Load()
+
+	if err := checkURL(policy, link.OriginalURL); err != nil {
+		return err
+	}
+	if policy.isReserved(link.Code) {
+		return ErrReservedAlias
+	}
+	if !entity.ValidRedirectType(link.RedirectType) {
+		return ErrInvalidRedirectType
+	}
+	if !link.ForwardQuery.Valid() {
+		return ErrInvalidQueryForwarding
+	}
+	return nil
+}
+
+// Restore saves an imported link as is once it passes Validate.
+func (s *Service) Restore(ctx context.Context, link entity.Link, overwrite bool) error {
+	if err := s.Validate(link); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, s.opts.WriteTimeout)
+	defer cancel()
+
+	return s.storage.Restore(ctx, link, overwrite)
+}
+
+func (s *Service) Delete(ctx context.Context, code string) error {
+	ctx, cancel := withTimeout(ctx, s.opts.WriteTimeout)
+	defer cancel()
+
+	return s.storage.Delete(ctx, code)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
@@ -0,0 +1,99 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+// sequence generates the given codes in order, then repeats the last one.
+func sequence(codes ...string) CodeGenerator {
+	return func(int) string {
+		code := codes[0]
+		if len(codes) > 1 {
+			codes = codes[1:]
+		}
+		return code
+	}
+}
+
+func TestShortenBatchConflicts(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		existing  []string
+		generated []string
+		reqs      []Request
+		wantCodes []string
+		wantErrs  []error
+	}{
+		{
+			name:      "generated codes retry past taken ones",
+			existing:  []string{"taken1"},
+			generated: []string{"taken1", "fresh1"},
+			reqs:      []Request{{URL: "https://a.example"}},
+			wantCodes: []string{"fresh1"},
+			wantErrs:  []error{nil},
+		},
+		{
+			name:      "taken alias is not retried",
+			existing:  []string{"custom"},
+			generated: []string{"fresh1"},
+			reqs:      []Request{{URL: "https://a.example", Alias: "custom"}, {URL: "https://b.example"}},
+			wantCodes: []string{"", "fresh1"},
+			wantErrs:  []error{ErrAliasTaken, nil},
+		},
+		{
+			name:      "alias repeated within the batch",
+			generated: []string{"fresh1"},
+			reqs:      []Request{{URL: "https://a.example", Alias: "custom"}, {URL: "https://b.example", Alias: "custom"}},
+			wantCodes: []string{"custom", ""},
+			wantErrs:  []error{nil, ErrAliasTaken},
+		},
+		{
+			name:      "generated codes give up after the last attempt",
+			existing:  []string{"taken1"},
+			generated: []string{"taken1"},
+			reqs:      []Request{{URL: "https://a.example"}},
+			wantCodes: []string{""},
+			wantErrs:  []error{storage.ErrConflict},
+		},
+		{
+			name:      "invalid requests fail alone",
+			generated: []string{"fresh1"},
+			reqs:      []Request{{URL: "ftp://a.example"}, {URL: "https://b.example"}},
+			wantCodes: []string{"", "fresh1"},
+			wantErrs:  []error{ErrInvalidURL, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStorage()
+			for _, code := range tt.existing {
+				if err := store.Save(ctx, entity.Link{Code: code, OriginalURL: "https://existing.example"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s := NewService(store, Options{Generator: sequence(tt.generated...)})
+			results, err := s.ShortenBatch(ctx, tt.reqs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, result := range results {
+				if !errors.Is(result.Err, tt.wantErrs[i]) {
+					t.Errorf("request %d: error %v, want %v", i, result.Err, tt.wantErrs[i])
+				}
+				if result.Link.Code != tt.wantCodes[i] {
+					t.Errorf("request %d: code %q, want %q", i, result.Link.Code, tt.wantCodes[i])
+				}
+			}
+		})
+	}
+}
@@ -1,31 +1,66 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Igorjr19/go-shorty/internal/entity"
 )
 
 type MemoryStorage struct {
 	data map[string]entity.Link
+	keys map[string]entity.APIKey
 	mu   sync.RWMutex
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
 		data: make(map[string]entity.Link),
+		keys: make(map[string]entity.APIKey),
 	}
 }
 
-func (m *MemoryStorage) Save(link entity.Link) error {
+func (m *MemoryStorage) Save(ctx context.Context, link entity.Link) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if _, exists := m.data[link.Code]; exists {
+		return ErrConflict
+	}
 	m.data[link.Code] = link
 	return nil
 }
 
-func (m *MemoryStorage) Load(code string) (entity.Link, error) {
+func (m *MemoryStorage) SaveBatch(ctx context.Context, links []entity.Link) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	errs := make([]error, len(links))
+	for i, link := range links {
+		if _, exists := m.data[link.Code]; exists {
+			errs[i] = ErrConflict
+			continue
+		}
+		m.data[link.Code] = link
+	}
+	return errs, nil
+}
+
+func (m *MemoryStorage) Load(ctx context.Context, code string) (entity.Link, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.Link{}, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	link, exists := m.data[code]
@@ -35,4 +70,299 @@ func (m *MemoryStorage) Load(code string) (entity.Link, error) {
 	return link, nil
 }
 
+func (m *MemoryStorage) List(ctx context.Context, opts ListOptions) ([]entity.Link, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	links := make([]entity.Link, 0, len(m.data))
+	for _, link := range m.data {
+		if matches(link, opts) {
+			links = append(links, link)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+		return links[i].Code < links[j].Code
+	})
+
+	return page(links, opts), nil
+}
+
+func (m *MemoryStorage) Each(ctx context.Context, opts ListOptions, fn func(entity.Link) error) error {
+	links, err := m.List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStorage) Restore(ctx context.Context, link entity.Link, overwrite bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.data[link.Code]; exists && !overwrite {
+		return ErrConflict
+	}
+	m.data[link.Code] = link
+	return nil
+}
+
+func (m *MemoryStorage) Delete(ctx context.Context, code string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.data[code]; !exists {
+		return ErrNotFound
+	}
+	delete(m.data, code)
+	return nil
+}
+
+func (m *MemoryStorage) RecordClick(ctx context.Context, code string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, exists := m.data[code]
+	if !exists {
+		return ErrNotFound
+	}
+	link.Clicks++
+	link.LastClickedAt = &at
+	m.data[code] = link
+	return nil
+}
+
+func (m *MemoryStorage) SetDisabled(ctx context.Context, code string, at *time.Time) error {
+	return m.update(ctx, code, func(link *entity.Link) {
+		link.DisabledAt = at
+	})
+}
+
+func (m *MemoryStorage) SetOwner(ctx context.Context, code, owner string) error {
+	return m.update(ctx, code, func(link *entity.Link) {
+		link.Owner = owner
+	})
+}
+
+func (m *MemoryStorage) ReassignOwner(ctx context.Context, from, to string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for code, link := range m.data {
+		if link.Owner == from {
+			link.Owner = to
+			m.data[code] = link
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStorage) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for code, link := range m.data {
+		if link.Expired(before) {
+			delete(m.data, code)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStorage) SetMetadata(ctx context.Context, code string, meta entity.Metadata, at time.Time) error {
+	return m.update(ctx, code, func(link *entity.Link) {
+		if meta.Title != "" {
+			link.Title = meta.Title
+		}
+		link.Description = meta.Description
+		link.FaviconURL = meta.FaviconURL
+		link.ImageURL = meta.ImageURL
+		link.MetadataFetchedAt = &at
+	})
+}
+
+func (m *MemoryStorage) StaleMetadata(ctx context.Context, before time.Time, limit int) ([]entity.Link, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	var links []entity.Link
+	for _, link := range m.data {
+		if link.DisabledAt == nil && (link.MetadataFetchedAt == nil || link.MetadataFetchedAt.Before(before)) {
+			links = append(links, link)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i].MetadataFetchedAt, links[j].MetadataFetchedAt
+		switch {
+		case a == nil && b == nil:
+			return links[i].CreatedAt.Before(links[j].CreatedAt)
+		case a == nil || b == nil:
+			return a == nil
+		case !a.Equal(*b):
+			return a.Before(*b)
+		default:
+			return links[i].CreatedAt.Before(links[j].CreatedAt)
+		}
+	})
+
+	if limit > 0 && limit < len(links) {
+		links = links[:limit]
+	}
+	return links, nil
+}
+
+func (m *MemoryStorage) SaveAPIKey(ctx context.Context, key entity.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.keys {
+		if existing.ID == key.ID || existing.Hash == key.Hash {
+			return ErrConflict
+		}
+	}
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *MemoryStorage) ListAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	keys := make([]entity.APIKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (m *MemoryStorage) FindAPIKey(ctx context.Context, hash string) (entity.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.APIKey{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.keys {
+		if key.Hash == hash && key.RevokedAt == nil {
+			return key, nil
+		}
+	}
+	return entity.APIKey{}, ErrNotFound
+}
+
+func (m *MemoryStorage) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, exists := m.keys[id]
+	if !exists || key.RevokedAt != nil {
+		return ErrNotFound
+	}
+	key.RevokedAt = &at
+	m.keys[id] = key
+	return nil
+}
+
+func (m *MemoryStorage) update(ctx context.Context, code string, fn func(*entity.Link)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, exists := m.data[code]
+	if !exists {
+		return ErrNotFound
+	}
+	fn(&link)
+	m.data[code] = link
+	return nil
+}
+
+func matches(link entity.Link, opts ListOptions) bool {
+	if opts.Query != "" {
+		q := strings.ToLower(opts.Query)
+		if !strings.Contains(strings.ToLower(link.Code), q) && !strings.Contains(strings.ToLower(link.OriginalURL), q) {
+			return false
+		}
+	}
+	if opts.Owner != "" && link.Owner != opts.Owner {
+		return false
+	}
+	if opts.OnlyDisabled && link.DisabledAt == nil {
+		return false
+	}
+	if opts.ExpiredBefore != nil && !link.Expired(*opts.ExpiredBefore) {
+		return false
+	}
+	if opts.CreatedAfter != nil && link.CreatedAt.Before(*opts.CreatedAfter) {
+		return false
+	}
+	if opts.CreatedBefore != nil && !link.CreatedAt.Before(*opts.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func page(links []entity.Link, opts ListOptions) []entity.Link {
+	if opts.Offset >= len(links) {
+		return []entity.Link{}
+	}
+	links = links[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(links) {
+		links = links[:opts.Limit]
+	}
+	return links
+}
+
 var ErrNotFound = fmt.Errorf("link not found")
+
+var ErrConflict = fmt.Errorf("link code already exists")
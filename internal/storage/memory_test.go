@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestMemorySaveBatch(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	if err := m.Save(ctx, entity.Link{Code: "taken", OriginalURL: "https://old.example"}); err != nil {
+		t.Fatal(err)
+	}
+
+	links := []entity.Link{
+		{Code: "first", OriginalURL: "https://one.example"},
+		{Code: "taken", OriginalURL: "https://two.example"},
+		{Code: "twice", OriginalURL: "https://three.example"},
+		{Code: "twice", OriginalURL: "https://four.example"},
+	}
+	errs, err := m.SaveBatch(ctx, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []error{nil, ErrConflict, nil, ErrConflict}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("link %d (%s): got %v, want %v", i, links[i].Code, errs[i], want[i])
+		}
+	}
+
+	for code, url := range map[string]string{
+		"first": "https://one.example",
+		"taken": "https://old.example",
+		"twice": "https://three.example",
+	} {
+		link, err := m.Load(ctx, code)
+		if err != nil {
+			t.Fatalf("Load(%q): %v", code, err)
+		}
+		if link.OriginalURL != url {
+			t.Errorf("Load(%q) leads to %s, want %s", code, link.OriginalURL, url)
+		}
+	}
+}
+
+func TestMemorySaveBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewMemoryStorage()
+	if _, err := m.SaveBatch(ctx, []entity.Link{{Code: "abc"}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SaveBatch() = %v, want context.Canceled", err)
+	}
+	if _, err := m.Load(context.Background(), "abc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("canceled batch saved a link: %v", err)
+	}
+}
@@ -1,12 +1,24 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 
-	"github.com/Igorjr19/go-shorty/internal/config"
 	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/logger"
+)
+
+const (
+	linkColumns   = `code, original_url, created_at, clicks, last_clicked_at, owner, disabled_at, expires_at, title, redirect_type, forward_path, forward_query, description, favicon_url, image_url, metadata_fetched_at`
+	apiKeyColumns = `id, name, owner, prefix, key_hash, created_at, revoked_at, admin`
 )
 
 type PostgresStorage struct {
@@ -14,26 +26,83 @@ type PostgresStorage struct {
 	pq.Config
 }
 
+type scanner interface {
+	Scan(dest ...any) error
+}
+
 func NewPostgresStorage(db *sql.DB) *PostgresStorage {
-	defer db.Close()
 	return &PostgresStorage{
-		db: config.ConnectDB(),
+		db: db,
 	}
-
 }
 
-func (p *PostgresStorage) Save(link entity.Link) error {
-	q := `INSERT INTO links (code, original_url, created_at) VALUES ($1, $2, $3)`
-	_, err := p.db.Exec(q, link.Code, link.OriginalURL, link.CreatedAt)
+func (p *PostgresStorage) Save(ctx context.Context, link entity.Link) error {
+	q := `INSERT INTO links (code, original_url, created_at, owner, expires_at, title, redirect_type, forward_path, forward_query)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := p.exec(ctx, q, link.Code, link.OriginalURL, link.CreatedAt, nullString(link.Owner), link.ExpiresAt, nullString(link.Title),
+		nullInt(link.RedirectType), link.ForwardPath, nullString(string(link.ForwardQuery)))
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
 	return err
 }
 
-func (p *PostgresStorage) Load(code string) (entity.Link, error) {
-	q := `SELECT code, original_url, created_at FROM links WHERE code = $1`
-	row := p.db.QueryRow(q, code)
+func (p *PostgresStorage) SaveBatch(ctx context.Context, links []entity.Link) ([]error, error) {
+	if len(links) == 0 {
+		return []error{}, nil
+	}
+
+	var q strings.Builder
+	q.WriteString(`INSERT INTO links (code, original_url, created_at, owner, expires_at, title, redirect_type, forward_path, forward_query) VALUES `)
+	args := make([]any, 0, len(links)*9)
+	for i, link := range links {
+		if i > 0 {
+			q.WriteString(`, `)
+		}
+		n := len(args)
+		fmt.Fprintf(&q, `($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)`, n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9)
+		args = append(args, link.Code, link.OriginalURL, link.CreatedAt, nullString(link.Owner), link.ExpiresAt, nullString(link.Title),
+			nullInt(link.RedirectType), link.ForwardPath, nullString(string(link.ForwardQuery)))
+	}
+	q.WriteString(` ON CONFLICT (code) DO NOTHING RETURNING code`)
+
+	inserted := make(map[string]bool, len(links))
+	err := p.query(ctx, q.String(), args, func(row scanner) error {
+		var code string
+		if err := row.Scan(&code); err != nil {
+			return err
+		}
+		inserted[code] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return batchErrors(links, inserted), nil
+}
+
+// batchErrors maps the codes a batch insert returned back to its links. A
+// code repeated within the batch is inserted once, for its first occurrence.
+func batchErrors(links []entity.Link, inserted map[string]bool) []error {
+	errs := make([]error, len(links))
+	for i, link := range links {
+		if inserted[link.Code] {
+			delete(inserted, link.Code)
+		} else {
+			errs[i] = ErrConflict
+		}
+	}
+	return errs
+}
+
+func (p *PostgresStorage) Load(ctx context.Context, code string) (entity.Link, error) {
+	q := `SELECT ` + linkColumns + ` FROM links WHERE code = $1`
 
 	var link entity.Link
-	err := row.Scan(&link.Code, &link.OriginalURL, &link.CreatedAt)
+	err := p.queryRow(ctx, q, []any{code}, func(row scanner) error {
+		return scanLink(row, &link)
+	})
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -43,3 +112,359 @@ func (p *PostgresStorage) Load(code string) (entity.Link, error) {
 	}
 	return link, nil
 }
+
+func (p *PostgresStorage) List(ctx context.Context, opts ListOptions) ([]entity.Link, error) {
+	links := []entity.Link{}
+	err := p.Each(ctx, opts, func(link entity.Link) error {
+		links = append(links, link)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (p *PostgresStorage) Each(ctx context.Context, opts ListOptions, fn func(entity.Link) error) error {
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if opts.Query != "" {
+		where(`(code ILIKE ? OR original_url ILIKE ?)`, "%"+escapeLike(opts.Query)+"%")
+	}
+	if opts.Owner != "" {
+		where(`owner = ?`, opts.Owner)
+	}
+	if opts.OnlyDisabled {
+		conditions = append(conditions, `disabled_at IS NOT NULL`)
+	}
+	if opts.ExpiredBefore != nil {
+		where(`expires_at <= ?`, *opts.ExpiredBefore)
+	}
+	if opts.CreatedAfter != nil {
+		where(`created_at >= ?`, *opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		where(`created_at < ?`, *opts.CreatedBefore)
+	}
+
+	q := `SELECT ` + linkColumns + ` FROM links`
+	if len(conditions) > 0 {
+		q += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	var limit any
+	if opts.Limit > 0 {
+		limit = opts.Limit
+	}
+	args = append(args, limit, opts.Offset)
+	q += fmt.Sprintf(` ORDER BY created_at DESC, code LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	return p.query(ctx, q, args, func(row scanner) error {
+		var link entity.Link
+		if err := scanLink(row, &link); err != nil {
+			return err
+		}
+		return fn(link)
+	})
+}
+
+func (p *PostgresStorage) Restore(ctx context.Context, link entity.Link, overwrite bool) error {
+	q := `INSERT INTO links (` + linkColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	if overwrite {
+		q += ` ON CONFLICT (code) DO UPDATE SET
+			original_url = EXCLUDED.original_url,
+			created_at = EXCLUDED.created_at,
+			clicks = EXCLUDED.clicks,
+			last_clicked_at = EXCLUDED.last_clicked_at,
+			owner = EXCLUDED.owner,
+			disabled_at = EXCLUDED.disabled_at,
+			expires_at = EXCLUDED.expires_at,
+			title = EXCLUDED.title,
+			redirect_type = EXCLUDED.redirect_type,
+			forward_path = EXCLUDED.forward_path,
+			forward_query = EXCLUDED.forward_query,
+			description = EXCLUDED.description,
+			favicon_url = EXCLUDED.favicon_url,
+			image_url = EXCLUDED.image_url,
+			metadata_fetched_at = EXCLUDED.metadata_fetched_at`
+	}
+
+	_, err := p.exec(ctx, q, link.Code, link.OriginalURL, link.CreatedAt, link.Clicks, link.LastClickedAt,
+		nullString(link.Owner), link.DisabledAt, link.ExpiresAt, nullString(link.Title), nullInt(link.RedirectType),
+		link.ForwardPath, nullString(string(link.ForwardQuery)),
+		nullString(link.Description), nullString(link.FaviconURL), nullString(link.ImageURL), link.MetadataFetchedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (p *PostgresStorage) Delete(ctx context.Context, code string) error {
+	result, err := p.exec(ctx, `DELETE FROM links WHERE code = $1`, code)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func (p *PostgresStorage) RecordClick(ctx context.Context, code string, at time.Time) error {
+	q := `UPDATE links SET clicks = clicks + 1, last_clicked_at = $2 WHERE code = $1`
+	result, err := p.exec(ctx, q, code, at)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func (p *PostgresStorage) SetDisabled(ctx context.Context, code string, at *time.Time) error {
+	result, err := p.exec(ctx, `UPDATE links SET disabled_at = $2 WHERE code = $1`, code, at)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func (p *PostgresStorage) SetOwner(ctx context.Context, code, owner string) error {
+	result, err := p.exec(ctx, `UPDATE links SET owner = $2 WHERE code = $1`, code, nullString(owner))
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func (p *PostgresStorage) ReassignOwner(ctx context.Context, from, to string) (int64, error) {
+	result, err := p.exec(ctx, `UPDATE links SET owner = $2 WHERE owner = $1`, from, nullString(to))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (p *PostgresStorage) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := p.exec(ctx, `DELETE FROM links WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (p *PostgresStorage) SetMetadata(ctx context.Context, code string, meta entity.Metadata, at time.Time) error {
+	q := `UPDATE links SET title = COALESCE($2, title), description = $3, favicon_url = $4, image_url = $5, metadata_fetched_at = $6 WHERE code = $1`
+	result, err := p.exec(ctx, q, code, nullString(meta.Title), nullString(meta.Description),
+		nullString(meta.FaviconURL), nullString(meta.ImageURL), at)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func (p *PostgresStorage) StaleMetadata(ctx context.Context, before time.Time, limit int) ([]entity.Link, error) {
+	q := `SELECT ` + linkColumns + ` FROM links
+		WHERE disabled_at IS NULL AND (metadata_fetched_at IS NULL OR metadata_fetched_at < $1)
+		ORDER BY metadata_fetched_at NULLS FIRST, created_at
+		LIMIT $2`
+
+	links := []entity.Link{}
+	err := p.query(ctx, q, []any{before, limit}, func(row scanner) error {
+		var link entity.Link
+		if err := scanLink(row, &link); err != nil {
+			return err
+		}
+		links = append(links, link)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (p *PostgresStorage) SaveAPIKey(ctx context.Context, key entity.APIKey) error {
+	q := `INSERT INTO api_keys (id, name, owner, prefix, key_hash, created_at, admin) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := p.exec(ctx, q, key.ID, key.Name, key.Owner, key.Prefix, key.Hash, key.CreatedAt, key.Admin)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (p *PostgresStorage) ListAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at`
+
+	keys := []entity.APIKey{}
+	err := p.query(ctx, q, nil, func(row scanner) error {
+		var key entity.APIKey
+		if err := scanAPIKey(row, &key); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (p *PostgresStorage) FindAPIKey(ctx context.Context, hash string) (entity.APIKey, error) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	var key entity.APIKey
+	err := p.queryRow(ctx, q, []any{hash}, func(row scanner) error {
+		return scanAPIKey(row, &key)
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.APIKey{}, ErrNotFound
+		}
+		return entity.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (p *PostgresStorage) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	if err := uuid.Validate(id); err != nil {
+		return ErrNotFound
+	}
+
+	q := `UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`
+	result, err := p.exec(ctx, q, id, at)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func scanLink(row scanner, link *entity.Link) error {
+	var (
+		lastClickedAt, disabledAt, expiresAt, fetchedAt         sql.NullTime
+		owner, title, forwardQuery, description, favicon, image sql.NullString
+		redirectType                                            sql.NullInt64
+	)
+	err := row.Scan(&link.Code, &link.OriginalURL, &link.CreatedAt, &link.Clicks, &lastClickedAt, &owner, &disabledAt, &expiresAt,
+		&title, &redirectType, &link.ForwardPath, &forwardQuery, &description, &favicon, &image, &fetchedAt)
+	if err != nil {
+		return err
+	}
+
+	link.LastClickedAt = timePtr(lastClickedAt)
+	link.Owner = owner.String
+	link.DisabledAt = timePtr(disabledAt)
+	link.ExpiresAt = timePtr(expiresAt)
+	link.Title = title.String
+	link.RedirectType = int(redirectType.Int64)
+	link.ForwardQuery = entity.QueryForwarding(forwardQuery.String)
+	link.Description = description.String
+	link.FaviconURL = favicon.String
+	link.ImageURL = image.String
+	link.MetadataFetchedAt = timePtr(fetchedAt)
+	return nil
+}
+
+func scanAPIKey(row scanner, key *entity.APIKey) error {
+	var revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Owner, &key.Prefix, &key.Hash, &key.CreatedAt, &revokedAt, &key.Admin); err != nil {
+		return err
+	}
+	key.RevokedAt = timePtr(revokedAt)
+	return nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func requireRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStorage) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := p.db.ExecContext(ctx, query, args...)
+
+	rows := int64(-1)
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			rows = n
+		}
+	}
+
+	logger.DatabaseQuery(ctx, query, args, rows, time.Since(start), err)
+	return result, err
+}
+
+func (p *PostgresStorage) queryRow(ctx context.Context, query string, args []any, scan func(scanner) error) error {
+	start := time.Now()
+	err := scan(p.db.QueryRowContext(ctx, query, args...))
+
+	rows := int64(1)
+	logErr := err
+	if errors.Is(err, sql.ErrNoRows) {
+		rows, logErr = 0, nil
+	} else if err != nil {
+		rows = -1
+	}
+
+	logger.DatabaseQuery(ctx, query, args, rows, time.Since(start), logErr)
+	return err
+}
+
+func (p *PostgresStorage) query(ctx context.Context, query string, args []any, scan func(scanner) error) error {
+	start := time.Now()
+	rows := int64(0)
+
+	err := func() error {
+		result, err := p.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+
+		for result.Next() {
+			if err := scan(result); err != nil {
+				return err
+			}
+			rows++
+		}
+		return result.Err()
+	}()
+
+	logger.DatabaseQuery(ctx, query, args, rows, time.Since(start), err)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestBatchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []string
+		inserted []string
+		want     []error
+	}{
+		{
+			name:     "all inserted",
+			codes:    []string{"aaa", "bbb"},
+			inserted: []string{"bbb", "aaa"},
+			want:     []error{nil, nil},
+		},
+		{
+			name:     "taken before the batch",
+			codes:    []string{"aaa", "bbb", "ccc"},
+			inserted: []string{"aaa", "ccc"},
+			want:     []error{nil, ErrConflict, nil},
+		},
+		{
+			name:     "repeated within the batch",
+			codes:    []string{"aaa", "bbb", "aaa"},
+			inserted: []string{"aaa", "bbb"},
+			want:     []error{nil, nil, ErrConflict},
+		},
+		{
+			name:     "repeated and already taken",
+			codes:    []string{"aaa", "aaa"},
+			inserted: nil,
+			want:     []error{ErrConflict, ErrConflict},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := make([]entity.Link, len(tt.codes))
+			for i, code := range tt.codes {
+				links[i] = entity.Link{Code: code}
+			}
+			inserted := make(map[string]bool)
+			for _, code := range tt.inserted {
+				inserted[code] = true
+			}
+
+			got := batchErrors(links, inserted)
+			if len(got) != len(tt.want) {
+				t.Fatalf("batchErrors() returned %d errors, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("link %d (%s): got %v, want %v", i, tt.codes[i], got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,76 @@
 package storage
 
-import "github.com/Igorjr19/go-shorty/internal/entity"
+import (
+	"context"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
 
 type Storage interface {
-	Save(entity.Link) error
-	Load(code string) (entity.Link, error)
+	Save(ctx context.Context, link entity.Link) error
+	// SaveBatch saves links in one round trip and returns an error per link:
+	// nil when saved, ErrConflict when its code was taken. The second error
+	// reports a failure of the whole batch.
+	SaveBatch(ctx context.Context, links []entity.Link) ([]error, error)
+	Load(ctx context.Context, code string) (entity.Link, error)
+	List(ctx context.Context, opts ListOptions) ([]entity.Link, error)
+	// Each calls fn for every link matching opts, in List order, without
+	// holding them all in memory. It stops at the first error fn returns.
+	Each(ctx context.Context, opts ListOptions, fn func(entity.Link) error) error
+	// Restore writes every field of link as given, replacing an existing
+	// link with the same code when overwrite is set and failing with
+	// ErrConflict otherwise.
+	Restore(ctx context.Context, link entity.Link, overwrite bool) error
+	Delete(ctx context.Context, code string) error
+	RecordClick(ctx context.Context, code string, at time.Time) error
+}
+
+// AdminStorage holds the operations only operators perform.
+type AdminStorage interface {
+	Storage
+	// SetDisabled disables the link at the given time, or enables it when at
+	// is nil.
+	SetDisabled(ctx context.Context, code string, at *time.Time) error
+	SetOwner(ctx context.Context, code, owner string) error
+	// ReassignOwner moves every link of one owner to another and returns how
+	// many were moved.
+	ReassignOwner(ctx context.Context, from, to string) (int64, error)
+	// PurgeExpired deletes links that expired at or before the given time and
+	// returns how many were deleted.
+	PurgeExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type APIKeyStorage interface {
+	SaveAPIKey(ctx context.Context, key entity.APIKey) error
+	ListAPIKeys(ctx context.Context) ([]entity.APIKey, error)
+	// FindAPIKey returns the active key with the given hash.
+	FindAPIKey(ctx context.Context, hash string) (entity.APIKey, error)
+	// RevokeAPIKey fails with ErrNotFound if the key is unknown or already
+	// revoked.
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+}
+
+// MetadataStorage keeps what is known about the page each link leads to.
+type MetadataStorage interface {
+	// SetMetadata records metadata fetched at the given time. An empty title
+	// keeps the one the link already has.
+	SetMetadata(ctx context.Context, code string, meta entity.Metadata, at time.Time) error
+	// StaleMetadata returns up to limit enabled links whose metadata was
+	// never fetched or was fetched before the given time, oldest first.
+	StaleMetadata(ctx context.Context, before time.Time, limit int) ([]entity.Link, error)
+}
+
+// ListOptions pages through links, newest first. The remaining fields narrow
+// the result and are ignored when zero.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Query matches part of the code or destination, case-insensitively.
+	Query         string
+	Owner         string
+	OnlyDisabled  bool
+	ExpiredBefore *time.Time
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
 }
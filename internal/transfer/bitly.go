@@ -0,0 +1,199 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+// Bitly has renamed its export columns over the years, so each field is
+// looked up under every name it has been seen with.
+var (
+	bitlyLongURL = []string{"long url", "destination url", "destination", "original url", "url"}
+	bitlyCustom  = []string{"custom bitlinks", "custom bitlink", "custom links", "custom link", "custom back half"}
+	bitlyLink    = []string{"bitlink", "short link", "short url", "link", "id"}
+	bitlyTitle   = []string{"title"}
+	bitlyCreated = []string{"created", "created at", "date created", "creation date"}
+	bitlyClicks  = []string{"clicks", "total clicks", "engagements"}
+)
+
+// looseTimeLayouts covers what Bitly and spreadsheet round trips produce.
+// Times without a zone are taken as UTC.
+var looseTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/2006",
+	"Jan 2, 2006",
+}
+
+// bitlyReader reads the CSV export of a Bitly account. A custom back-half
+// becomes the code; otherwise the back-half of the bitlink is kept.
+type bitlyReader struct {
+	rows    *headerReader
+	checked bool
+}
+
+func (r *bitlyReader) Line() int {
+	return r.rows.line
+}
+
+func (r *bitlyReader) Read() (entity.Link, error) {
+	if err := r.rows.next(); err != nil {
+		return entity.Link{}, err
+	}
+
+	if !r.checked {
+		r.checked = true
+		if !r.rows.has(bitlyLongURL...) || !(r.rows.has(bitlyLink...) || r.rows.has(bitlyCustom...)) {
+			return entity.Link{}, errors.New("not a Bitly export: expected a long URL and a bitlink column")
+		}
+	}
+
+	rec := record{
+		Code:        bitlyCode(r.rows.get(bitlyCustom...), r.rows.get(bitlyLink...)),
+		OriginalURL: r.rows.get(bitlyLongURL...),
+		Title:       r.rows.get(bitlyTitle...),
+	}
+
+	fail := func(column string, err error) (entity.Link, error) {
+		return entity.Link{}, &RowError{Line: r.rows.line, Code: rec.Code, Err: fmt.Errorf("%s: %w", column, err)}
+	}
+
+	if v := strings.ReplaceAll(r.rows.get(bitlyClicks...), ",", ""); v != "" {
+		clicks, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fail("clicks", err)
+		}
+		rec.Clicks = clicks
+	}
+
+	if v := r.rows.get(bitlyCreated...); v != "" {
+		created, err := parseLooseTime(v)
+		if err != nil {
+			return fail("created", err)
+		}
+		rec.CreatedAt = created
+	}
+
+	return rec.link(r.rows.line)
+}
+
+// bitlyCode prefers the first custom back-half, which is the alias users
+// know the link by.
+func bitlyCode(custom, bitlink string) string {
+	for _, candidate := range strings.FieldsFunc(custom, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';' || r == '|'
+	}) {
+		return backHalf(candidate)
+	}
+	return backHalf(bitlink)
+}
+
+// backHalf returns the path of a short link, e.g. "campaign" for
+// "https://bit.ly/campaign?x=1".
+func backHalf(link string) string {
+	link = strings.TrimSpace(link)
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		link = link[:i]
+	}
+	link = strings.TrimRight(link, "/")
+	if i := strings.LastIndex(link, "/"); i >= 0 {
+		link = link[i+1:]
+	}
+	return link
+}
+
+func parseLooseTime(value string) (time.Time, error) {
+	for _, layout := range looseTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// headerReader reads CSV rows by column name, ignoring case, spacing and
+// punctuation differences between header spellings.
+type headerReader struct {
+	csv   *csv.Reader
+	index map[string]int
+	row   []string
+	line  int
+}
+
+func newHeaderReader(r io.Reader) *headerReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return &headerReader{csv: cr}
+}
+
+func (h *headerReader) next() error {
+	if h.index == nil {
+		header, err := h.csv.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.EOF
+			}
+			return fmt.Errorf("read CSV header: %w", err)
+		}
+
+		h.index = make(map[string]int, len(header))
+		for i, name := range header {
+			h.index[normalizeHeader(name)] = i
+		}
+	}
+
+	row, err := h.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		h.line = parseErr.StartLine
+		return &RowError{Line: h.line, Err: parseErr.Err}
+	}
+	if err != nil {
+		return err
+	}
+
+	h.row = row
+	h.line, _ = h.csv.FieldPos(0)
+	return nil
+}
+
+func (h *headerReader) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := h.index[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the first non-empty value among the named columns.
+func (h *headerReader) get(names ...string) string {
+	for _, name := range names {
+		if i, ok := h.index[name]; ok && i < len(h.row) {
+			if v := strings.TrimSpace(h.row[i]); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func normalizeHeader(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+	name = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
@@ -0,0 +1,168 @@
+package transfer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestBitlyCode(t *testing.T) {
+	tests := []struct {
+		custom  string
+		bitlink string
+		want    string
+	}{
+		{"", "https://bit.ly/3xYz9Ab", "3xYz9Ab"},
+		{"", "bit.ly/3xYz9Ab/", "3xYz9Ab"},
+		{"", "https://bit.ly/3xYz9Ab?utm=x#top", "3xYz9Ab"},
+		{"", "3xYz9Ab", "3xYz9Ab"},
+		{"https://bit.ly/spring-sale", "https://bit.ly/3xYz9Ab", "spring-sale"},
+		{"bit.ly/first, bit.ly/second", "https://bit.ly/3xYz9Ab", "first"},
+		{"  ;| brand.co/launch", "https://bit.ly/3xYz9Ab", "launch"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := bitlyCode(tt.custom, tt.bitlink); got != tt.want {
+			t.Errorf("bitlyCode(%q, %q) = %q, want %q", tt.custom, tt.bitlink, got, tt.want)
+		}
+	}
+}
+
+func TestParseLooseTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2021-06-15T12:30:45Z", time.Date(2021, 6, 15, 12, 30, 45, 0, time.UTC)},
+		{"2021-06-15T12:30:45+0200", time.Date(2021, 6, 15, 10, 30, 45, 0, time.UTC)},
+		{"2021-06-15 12:30:45", time.Date(2021, 6, 15, 12, 30, 45, 0, time.UTC)},
+		{"2021-06-15", time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"6/15/2021 12:30", time.Date(2021, 6, 15, 12, 30, 0, 0, time.UTC)},
+		{"Jun 15, 2021", time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLooseTime(tt.value)
+		if err != nil {
+			t.Errorf("parseLooseTime(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseLooseTime(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := parseLooseTime("last Tuesday"); err == nil {
+		t.Error("parseLooseTime accepted an unknown format")
+	}
+}
+
+func TestBitlyReader(t *testing.T) {
+	created := time.Date(2021, 6, 15, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		csv         string
+		want        []entity.Link
+		wantInvalid []int
+		wantErr     bool
+	}{
+		{
+			name: "current export",
+			csv: "\ufeffBitlink,Custom bitlinks,Long URL,Title,Created,Clicks\n" +
+				"https://bit.ly/3xYz9Ab,https://bit.ly/spring-sale,https://shop.example/sale,\"Sale, 50% off\",2021-06-15T12:30:45Z,\"1,204\"\n" +
+				"https://bit.ly/4aBc,,https://example.com,,2021-06-15 12:30:45,\n",
+			want: []entity.Link{
+				{Code: "spring-sale", OriginalURL: "https://shop.example/sale", Title: "Sale, 50% off", CreatedAt: created, Clicks: 1204},
+				{Code: "4aBc", OriginalURL: "https://example.com", CreatedAt: created},
+			},
+		},
+		{
+			name: "older column names",
+			csv: "id,long_url,title,created_at,total_clicks\n" +
+				"bit.ly/3xYz9Ab,https://example.com,Home,6/15/2021 12:30:45,7\n",
+			want: []entity.Link{{Code: "3xYz9Ab", OriginalURL: "https://example.com", Title: "Home", CreatedAt: created, Clicks: 7}},
+		},
+		{
+			name: "custom back-half column alone",
+			csv: "Destination URL,Custom Back-Half\n" +
+				"https://example.com,launch\n",
+			want: []entity.Link{{Code: "launch", OriginalURL: "https://example.com"}},
+		},
+		{
+			name: "invalid rows are reported by line",
+			csv: "Bitlink,Long URL,Created,Clicks\n" +
+				"https://bit.ly/ok,https://example.com,,\n" +
+				"https://bit.ly/clicks,https://example.com,,lots\n" +
+				"https://bit.ly/date,https://example.com,someday,\n" +
+				"https://bit.ly/ftp,ftp://files.example,,\n" +
+				",https://example.com,,\n",
+			want:        []entity.Link{{Code: "ok", OriginalURL: "https://example.com"}},
+			wantInvalid: []int{3, 4, 5, 6},
+		},
+		{
+			name:    "not a Bitly export",
+			csv:     "code,original_url\nabc,https://example.com\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.csv), Bitly)
+			if tt.wantErr {
+				if _, err := r.Read(); err == nil {
+					t.Fatal("Read() succeeded, want an error")
+				}
+				return
+			}
+
+			links, invalid := readAll(t, r)
+			if !reflect.DeepEqual(links, tt.want) {
+				t.Errorf("links\n got %+v\nwant %+v", links, tt.want)
+			}
+			if !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Errorf("invalid lines %v, want %v", invalid, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestImportBitlyReport(t *testing.T) {
+	export := "Bitlink,Custom bitlinks,Long URL\n" +
+		"https://bit.ly/aaa,https://bit.ly/taken,https://one.example\n" +
+		"https://bit.ly/bbb,,https://two.example\n" +
+		"https://bit.ly/bbb,,https://three.example\n" +
+		"https://bit.ly/ccc,,javascript:alert(1)\n"
+	dst := target{"taken": {Code: "taken", OriginalURL: "https://old.example"}}
+
+	for _, dryRun := range []bool{false, true} {
+		report, err := Import(context.Background(), dst, NewReader(strings.NewReader(export), Bitly), ImportOptions{DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Report{
+			DryRun:   dryRun,
+			Imported: 1,
+			Skipped: []Issue{
+				{Line: 2, Code: "taken", Reason: "code already exists"},
+				{Line: 4, Code: "bbb", Reason: "code already exists"},
+			},
+			Invalid: []Issue{{Line: 5, Code: "ccc", Reason: `invalid original_url "javascript:alert(1)"`}},
+		}
+		if dryRun {
+			// The first run already imported bbb.
+			want.Imported = 0
+			want.Skipped = []Issue{want.Skipped[0], {Line: 3, Code: "bbb", Reason: "code already exists"}, want.Skipped[1]}
+		}
+		if !reflect.DeepEqual(report, want) {
+			t.Errorf("dry run %v: report\n got %+v\nwant %+v", dryRun, report, want)
+		}
+	}
+}
@@ -0,0 +1,476 @@
+// Package transfer moves links in and out of go-shorty as CSV or NDJSON, one
+// link per row with every stored field. It also reads the exports of Bitly and
+// YOURLS.
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+type Format string
+
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+	// Bitly and YOURLS can only be imported.
+	Bitly  Format = "bitly"
+	YOURLS Format = "yourls"
+)
+
+var (
+	ErrUnknownFormat       = errors.New("format must be csv or ndjson")
+	ErrUnknownImportFormat = errors.New("format must be csv, ndjson, bitly or yourls")
+)
+
+var columns = []string{"code", "original_url", "created_at", "clicks", "last_clicked_at", "owner", "expires_at", "disabled_at", "title", "redirect_type", "forward_path", "forward_query", "description", "favicon_url", "image_url", "metadata_fetched_at"}
+
+// Codes from other shorteners may be shorter than our aliases, but must still
+// be safe to put in a path.
+var codePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+// ParseFormat accepts the formats links can be exported to.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "csv":
+		return CSV, nil
+	case "ndjson", "jsonl":
+		return NDJSON, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// ParseImportFormat also accepts the formats of other shorteners.
+func ParseImportFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Bitly, YOURLS:
+		return f, nil
+	default:
+		f, err := ParseFormat(s)
+		if err != nil {
+			return "", ErrUnknownImportFormat
+		}
+		return f, nil
+	}
+}
+
+// FormatFromPath picks the format from a file extension, defaulting to CSV.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return NDJSON
+	default:
+		return CSV
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == NDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type record struct {
+	Code          string     `json:"code"`
+	OriginalURL   string     `json:"original_url"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Clicks        int64      `json:"clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+	Owner         string     `json:"owner,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	RedirectType  int        `json:"redirect_type,omitempty"`
+	ForwardPath   bool       `json:"forward_path,omitempty"`
+	ForwardQuery  string     `json:"forward_query,omitempty"`
+
+	Description       string     `json:"description,omitempty"`
+	FaviconURL        string     `json:"favicon_url,omitempty"`
+	ImageURL          string     `json:"image_url,omitempty"`
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at,omitempty"`
+}
+
+type Writer struct {
+	format      Format
+	csv         *csv.Writer
+	json        *json.Encoder
+	wroteHeader bool
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	if format == NDJSON {
+		return &Writer{format: format, json: json.NewEncoder(w)}
+	}
+	return &Writer{format: CSV, csv: csv.NewWriter(w)}
+}
+
+func (w *Writer) Write(link entity.Link) error {
+	if w.format == NDJSON {
+		return w.json.Encode(record{
+			Code:          link.Code,
+			OriginalURL:   link.OriginalURL,
+			CreatedAt:     link.CreatedAt,
+			Clicks:        link.Clicks,
+			LastClickedAt: link.LastClickedAt,
+			Owner:         link.Owner,
+			ExpiresAt:     link.ExpiresAt,
+			DisabledAt:    link.DisabledAt,
+			Title:         link.Title,
+			RedirectType:  link.RedirectType,
+			ForwardPath:   link.ForwardPath,
+			ForwardQuery:  string(link.ForwardQuery),
+
+			Description:       link.Description,
+			FaviconURL:        link.FaviconURL,
+			ImageURL:          link.ImageURL,
+			MetadataFetchedAt: link.MetadataFetchedAt,
+		})
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.csv.Write([]string{
+		link.Code,
+		link.OriginalURL,
+		formatTime(&link.CreatedAt),
+		strconv.FormatInt(link.Clicks, 10),
+		formatTime(link.LastClickedAt),
+		link.Owner,
+		formatTime(link.ExpiresAt),
+		formatTime(link.DisabledAt),
+		link.Title,
+		formatInt(link.RedirectType),
+		formatBool(link.ForwardPath),
+		string(link.ForwardQuery),
+		link.Description,
+		link.FaviconURL,
+		link.ImageURL,
+		formatTime(link.MetadataFetchedAt),
+	})
+}
+
+// Flush writes buffered rows, and the CSV header if no row was written.
+func (w *Writer) Flush() error {
+	if w.format == NDJSON {
+		return nil
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *Writer) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	return w.csv.Write(columns)
+}
+
+// RowError reports a row that cannot be imported. Reading can continue with
+// the next row.
+type RowError struct {
+	Line int
+	Code string
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// LinkReader yields imported links one at a time.
+type LinkReader interface {
+	// Read returns the next link, or io.EOF at the end of the input. A row
+	// that cannot be used is returned as a *RowError; any other error ends
+	// the input. A missing creation time is left zero for the caller.
+	Read() (entity.Link, error)
+	// Line is where the last row returned by Read starts.
+	Line() int
+}
+
+// NewReader reads links in any import format.
+func NewReader(r io.Reader, format Format) LinkReader {
+	switch format {
+	case NDJSON:
+		lines := bufio.NewScanner(r)
+		lines.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		return &nativeReader{format: format, lines: lines}
+	case Bitly:
+		return &bitlyReader{rows: newHeaderReader(r)}
+	case YOURLS:
+		return &yourlsReader{sql: newSQLScanner(r)}
+	default:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		return &nativeReader{format: CSV, csv: cr}
+	}
+}
+
+type nativeReader struct {
+	format Format
+	csv    *csv.Reader
+	header map[string]int
+	lines  *bufio.Scanner
+	line   int
+}
+
+func (r *nativeReader) Line() int {
+	return r.line
+}
+
+func (r *nativeReader) Read() (entity.Link, error) {
+	var (
+		rec record
+		err error
+	)
+	if r.format == NDJSON {
+		rec, err = r.readJSON()
+	} else {
+		rec, err = r.readCSV()
+	}
+	if err != nil {
+		return entity.Link{}, err
+	}
+
+	return rec.link(r.line)
+}
+
+// link validates the record and converts it, reporting problems as a
+// *RowError for line.
+func (rec record) link(line int) (entity.Link, error) {
+	if err := validate(rec); err != nil {
+		return entity.Link{}, &RowError{Line: line, Code: rec.Code, Err: err}
+	}
+
+	return entity.Link{
+		Code:          rec.Code,
+		OriginalURL:   rec.OriginalURL,
+		Title:         rec.Title,
+		RedirectType:  rec.RedirectType,
+		ForwardPath:   rec.ForwardPath,
+		ForwardQuery:  entity.QueryForwarding(rec.ForwardQuery),
+		CreatedAt:     rec.CreatedAt,
+		Clicks:        rec.Clicks,
+		LastClickedAt: rec.LastClickedAt,
+		Owner:         rec.Owner,
+		ExpiresAt:     rec.ExpiresAt,
+		DisabledAt:    rec.DisabledAt,
+
+		Description:       rec.Description,
+		FaviconURL:        rec.FaviconURL,
+		ImageURL:          rec.ImageURL,
+		MetadataFetchedAt: rec.MetadataFetchedAt,
+	}, nil
+}
+
+func (r *nativeReader) readJSON() (record, error) {
+	for r.lines.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.lines.Text())
+		if line == "" {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return record{}, &RowError{Line: r.line, Err: err}
+		}
+		return rec, nil
+	}
+
+	if err := r.lines.Err(); err != nil {
+		return record{}, err
+	}
+	return record{}, io.EOF
+}
+
+func (r *nativeReader) readCSV() (record, error) {
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return record{}, err
+		}
+	}
+
+	row, err := r.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		r.line = parseErr.StartLine
+		return record{}, &RowError{Line: r.line, Err: parseErr.Err}
+	}
+	if err != nil {
+		return record{}, err
+	}
+	r.line, _ = r.csv.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := r.header[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	rec := record{
+		Code:         field("code"),
+		OriginalURL:  field("original_url"),
+		Owner:        field("owner"),
+		Title:        field("title"),
+		ForwardQuery: field("forward_query"),
+		Description:  field("description"),
+		FaviconURL:   field("favicon_url"),
+		ImageURL:     field("image_url"),
+	}
+
+	fail := func(column string, err error) (record, error) {
+		return record{}, &RowError{Line: r.line, Code: rec.Code, Err: fmt.Errorf("%s: %w", column, err)}
+	}
+
+	if v := field("clicks"); v != "" {
+		if rec.Clicks, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fail("clicks", err)
+		}
+	}
+	if v := field("redirect_type"); v != "" {
+		if rec.RedirectType, err = strconv.Atoi(v); err != nil {
+			return fail("redirect_type", err)
+		}
+	}
+	if v := field("forward_path"); v != "" {
+		if rec.ForwardPath, err = strconv.ParseBool(v); err != nil {
+			return fail("forward_path", err)
+		}
+	}
+
+	for _, t := range []struct {
+		column string
+		dst    **time.Time
+	}{
+		{"last_clicked_at", &rec.LastClickedAt},
+		{"expires_at", &rec.ExpiresAt},
+		{"disabled_at", &rec.DisabledAt},
+		{"metadata_fetched_at", &rec.MetadataFetchedAt},
+	} {
+		if *t.dst, err = parseTime(field(t.column)); err != nil {
+			return fail(t.column, err)
+		}
+	}
+
+	createdAt, err := parseTime(field("created_at"))
+	if err != nil {
+		return fail("created_at", err)
+	}
+	if createdAt != nil {
+		rec.CreatedAt = *createdAt
+	}
+
+	return rec, nil
+}
+
+func (r *nativeReader) readHeader() error {
+	row, err := r.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("read CSV header: %w", err)
+	}
+
+	r.header = make(map[string]int, len(row))
+	for i, name := range row {
+		r.header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"code", "original_url"} {
+		if _, ok := r.header[required]; !ok {
+			return fmt.Errorf("CSV header must include %q", required)
+		}
+	}
+	return nil
+}
+
+func validate(rec record) error {
+	if !codePattern.MatchString(rec.Code) {
+		return fmt.Errorf("invalid code %q", rec.Code)
+	}
+
+	u, err := url.Parse(rec.OriginalURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid original_url %q", rec.OriginalURL)
+	}
+
+	for _, u := range []struct{ column, value string }{
+		{"favicon_url", rec.FaviconURL},
+		{"image_url", rec.ImageURL},
+	} {
+		if u.value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u.value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid %s %q", u.column, u.value)
+		}
+	}
+
+	if rec.Clicks < 0 {
+		return errors.New("clicks must not be negative")
+	}
+	if !entity.ValidRedirectType(rec.RedirectType) {
+		return fmt.Errorf("invalid redirect_type %d", rec.RedirectType)
+	}
+	if !entity.QueryForwarding(rec.ForwardQuery).Valid() {
+		return fmt.Errorf("invalid forward_query %q", rec.ForwardQuery)
+	}
+	return nil
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func formatBool(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
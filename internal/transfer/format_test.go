@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	clicked := time.Date(2024, 6, 1, 8, 30, 0, 123456789, time.UTC)
+	fetched := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	links := []entity.Link{
+		{
+			Code:              "docs",
+			OriginalURL:       "https://example.com/docs?a=1&b=2",
+			Title:             `Docs, "quoted"`,
+			CreatedAt:         created,
+			Clicks:            42,
+			LastClickedAt:     &clicked,
+			Owner:             "platform",
+			ExpiresAt:         &expires,
+			DisabledAt:        &clicked,
+			RedirectType:      301,
+			ForwardPath:       true,
+			ForwardQuery:      entity.QueryPreferLink,
+			Description:       "Line one\nline two",
+			FaviconURL:        "https://example.com/favicon.ico",
+			ImageURL:          "https://cdn.example.com/cover.png",
+			MetadataFetchedAt: &fetched,
+		},
+		{
+			Code:        "min",
+			OriginalURL: "http://example.org",
+			CreatedAt:   created,
+		},
+	}
+
+	for _, format := range []Format{CSV, NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, format)
+			for _, link := range links {
+				if err := w.Write(link); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			r := NewReader(&buf, format)
+			for i, want := range links {
+				got, err := r.Read()
+				if err != nil {
+					t.Fatalf("link %d: %v", i, err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("link %d:\n got %+v\nwant %+v", i, got, want)
+				}
+			}
+			if _, err := r.Read(); !errors.Is(err, io.EOF) {
+				t.Errorf("Read() after the last link = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestEmptyCSVExportHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, CSV)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(columns, ",") + "\n"; buf.String() != want {
+		t.Errorf("empty export = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     []string
+		wantRows []int
+	}{
+		{
+			name:  "columns in any order",
+			input: "Original_URL, code ,title\nhttps://example.com,abc,Home\n",
+			want:  []string{"abc"},
+		},
+		{
+			name:     "invalid rows are reported by line",
+			input:    "code,original_url,clicks,favicon_url\nok,https://example.com,,\nbad code,https://example.com,,\nneg,https://example.com,-1,\nicon,https://example.com,,javascript:alert(1)\nlast,https://example.com,3,\n",
+			want:     []string{"ok", "last"},
+			wantRows: []int{3, 4, 5},
+		},
+		{
+			name:     "unparsable times",
+			input:    "code,original_url,metadata_fetched_at\nabc,https://example.com,yesterday\n",
+			wantRows: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input), CSV)
+			var codes []string
+			var rows []int
+			for {
+				link, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				var rowErr *RowError
+				if errors.As(err, &rowErr) {
+					rows = append(rows, rowErr.Line)
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				codes = append(codes, link.Code)
+			}
+
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("codes %q, want %q", codes, tt.want)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("invalid lines %v, want %v", rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestReadCSVRequiresHeader(t *testing.T) {
+	r := NewReader(strings.NewReader("code,title\nabc,Home\n"), CSV)
+	if _, err := r.Read(); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("Read() = %v, want a header error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+// ConflictPolicy decides what happens to an imported code that already
+// exists.
+type ConflictPolicy string
+
+const (
+	Skip      ConflictPolicy = "skip"
+	Overwrite ConflictPolicy = "overwrite"
+	Fail      ConflictPolicy = "fail"
+)
+
+var ErrConflict = errors.New("code already exists")
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case Skip, Overwrite, Fail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("conflict policy must be skip, overwrite or fail, got %q", s)
+	}
+}
+
+// Target is where links are imported to, usually the shortener service.
+type Target interface {
+	// Validate rejects a link that must not be imported; the row is then
+	// reported as invalid.
+	Validate(link entity.Link) error
+	Get(ctx context.Context, code string) (entity.Link, error)
+	// Restore saves link as is, failing with storage.ErrConflict when the
+	// code exists unless overwrite is set.
+	Restore(ctx context.Context, link entity.Link, overwrite bool) error
+}
+
+// Source is what links are exported from, usually the shortener service.
+type Source interface {
+	Export(ctx context.Context, opts storage.ListOptions, fn func(entity.Link) error) error
+}
+
+type ImportOptions struct {
+	OnConflict ConflictPolicy
+	DryRun     bool
+	// Owner, when set, replaces the owner of every imported link, and links
+	// of other owners are treated as existing codes that are never
+	// overwritten.
+	Owner string
+}
+
+type Issue struct {
+	Line   int    `json:"line"`
+	Code   string `json:"code,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type Report struct {
+	DryRun      bool    `json:"dry_run"`
+	Imported    int     `json:"imported"`
+	Overwritten int     `json:"overwritten"`
+	Skipped     []Issue `json:"skipped,omitempty"`
+	Invalid     []Issue `json:"invalid,omitempty"`
+}
+
+// Import restores every valid row of r into dst. Invalid rows are reported
+// and skipped. With the Fail policy the import stops at the first existing
+// code; rows before it stay imported, so run with DryRun first to check.
+func Import(ctx context.Context, dst Target, r LinkReader, opts ImportOptions) (Report, error) {
+	report := Report{DryRun: opts.DryRun}
+	if opts.OnConflict == "" {
+		opts.OnConflict = Skip
+	}
+
+	// A dry run writes nothing, so codes repeated in the input are tracked
+	// here to report them as they would be.
+	seen := make(map[string]bool)
+	now := time.Now()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		link, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			report.Invalid = append(report.Invalid, Issue{Line: rowErr.Line, Code: rowErr.Code, Reason: rowErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+		if opts.Owner != "" {
+			link.Owner = opts.Owner
+		}
+
+		if err := dst.Validate(link); err != nil {
+			report.Invalid = append(report.Invalid, Issue{Line: r.Line(), Code: link.Code, Reason: err.Error()})
+			continue
+		}
+
+		// owned tells whether an existing code may be overwritten. Codes of
+		// other owners fail like any insert of a taken code, so the report
+		// does not tell them apart.
+		exists := opts.DryRun && seen[link.Code]
+		owned := exists
+		if !exists {
+			existing, err := dst.Get(ctx, link.Code)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return report, err
+			}
+			exists = err == nil
+			owned = exists && (opts.Owner == "" || existing.Owner == opts.Owner)
+		}
+
+		if !exists && !opts.DryRun {
+			err := dst.Restore(ctx, link, false)
+			if err != nil && !errors.Is(err, storage.ErrConflict) {
+				return report, err
+			}
+			// Created concurrently since the check above.
+			exists = err != nil
+			owned = exists && opts.Owner == ""
+		}
+
+		if !exists {
+			if opts.DryRun {
+				seen[link.Code] = true
+			}
+			report.Imported++
+			continue
+		}
+
+		policy := opts.OnConflict
+		if policy == Overwrite && !owned {
+			policy = Skip
+		}
+
+		switch policy {
+		case Overwrite:
+			if !opts.DryRun {
+				if err := dst.Restore(ctx, link, true); err != nil {
+					return report, err
+				}
+			}
+			report.Overwritten++
+		case Fail:
+			return report, fmt.Errorf("line %d: code %q: %w", r.Line(), link.Code, ErrConflict)
+		default:
+			report.Skipped = append(report.Skipped, Issue{Line: r.Line(), Code: link.Code, Reason: ErrConflict.Error()})
+		}
+	}
+}
+
+// Export writes every link matching opts to w and returns how many it wrote.
+func Export(ctx context.Context, src Source, w *Writer, opts storage.ListOptions) (int, error) {
+	n := 0
+	err := src.Export(ctx, opts, func(link entity.Link) error {
+		n++
+		return w.Write(link)
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, w.Flush()
+}
@@ -0,0 +1,130 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+// target keeps imported links in a map.
+type target map[string]entity.Link
+
+func (t target) Validate(link entity.Link) error {
+	if link.Code == "blocked" {
+		return errors.New("blocked")
+	}
+	return nil
+}
+
+func (t target) Get(_ context.Context, code string) (entity.Link, error) {
+	link, ok := t[code]
+	if !ok {
+		return entity.Link{}, storage.ErrNotFound
+	}
+	return link, nil
+}
+
+func (t target) Restore(_ context.Context, link entity.Link, overwrite bool) error {
+	if _, ok := t[link.Code]; ok && !overwrite {
+		return storage.ErrConflict
+	}
+	t[link.Code] = link
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	const input = "code,original_url,owner\n" +
+		"new,https://new.example,\n" +
+		"mine,https://mine.example,\n" +
+		"theirs,https://theirs.example,\n" +
+		"blocked,https://blocked.example,\n" +
+		"new,https://again.example,\n"
+
+	tests := []struct {
+		name       string
+		opts       ImportOptions
+		want       Report
+		wantErr    error
+		wantOwners map[string]string
+		wantURLs   map[string]string
+	}{
+		{
+			name: "skip",
+			opts: ImportOptions{OnConflict: Skip},
+			want: Report{
+				Imported: 1,
+				Skipped:  []Issue{{Line: 3, Code: "mine", Reason: "code already exists"}, {Line: 4, Code: "theirs", Reason: "code already exists"}, {Line: 6, Code: "new", Reason: "code already exists"}},
+				Invalid:  []Issue{{Line: 5, Code: "blocked", Reason: "blocked"}},
+			},
+			wantURLs: map[string]string{"new": "https://new.example", "mine": "https://old.example", "theirs": "https://old.example"},
+		},
+		{
+			name: "dry run tracks repeated codes",
+			opts: ImportOptions{OnConflict: Skip, DryRun: true},
+			want: Report{
+				DryRun:   true,
+				Imported: 1,
+				Skipped:  []Issue{{Line: 3, Code: "mine", Reason: "code already exists"}, {Line: 4, Code: "theirs", Reason: "code already exists"}, {Line: 6, Code: "new", Reason: "code already exists"}},
+				Invalid:  []Issue{{Line: 5, Code: "blocked", Reason: "blocked"}},
+			},
+			wantURLs: map[string]string{"mine": "https://old.example", "theirs": "https://old.example"},
+		},
+		{
+			name:     "overwrite",
+			opts:     ImportOptions{OnConflict: Overwrite},
+			want:     Report{Imported: 1, Overwritten: 3, Invalid: []Issue{{Line: 5, Code: "blocked", Reason: "blocked"}}},
+			wantURLs: map[string]string{"new": "https://again.example", "mine": "https://mine.example", "theirs": "https://theirs.example"},
+		},
+		{
+			name: "overwrite for an owner leaves other owners alone",
+			opts: ImportOptions{OnConflict: Overwrite, Owner: "alice"},
+			want: Report{
+				Imported:    1,
+				Overwritten: 2,
+				Skipped:     []Issue{{Line: 4, Code: "theirs", Reason: "code already exists"}},
+				Invalid:     []Issue{{Line: 5, Code: "blocked", Reason: "blocked"}},
+			},
+			wantOwners: map[string]string{"new": "alice", "mine": "alice", "theirs": "bob"},
+			wantURLs:   map[string]string{"new": "https://again.example", "mine": "https://mine.example", "theirs": "https://old.example"},
+		},
+		{
+			name:     "fail",
+			opts:     ImportOptions{OnConflict: Fail, Owner: "alice"},
+			want:     Report{Imported: 1},
+			wantErr:  ErrConflict,
+			wantURLs: map[string]string{"new": "https://new.example", "mine": "https://old.example"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := target{
+				"mine":   {Code: "mine", OriginalURL: "https://old.example", Owner: "alice"},
+				"theirs": {Code: "theirs", OriginalURL: "https://old.example", Owner: "bob"},
+			}
+
+			report, err := Import(context.Background(), dst, NewReader(strings.NewReader(input), CSV), tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Import() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(report, tt.want) {
+				t.Errorf("report\n got %+v\nwant %+v", report, tt.want)
+			}
+			for code, url := range tt.wantURLs {
+				if got := dst[code].OriginalURL; got != url {
+					t.Errorf("%s leads to %q, want %q", code, got, url)
+				}
+			}
+			for code, owner := range tt.wantOwners {
+				if got := dst[code].Owner; got != owner {
+					t.Errorf("%s is owned by %q, want %q", code, got, owner)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package transfer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+// yourlsColumns is the column order of the yourls_url table, used when an
+// INSERT does not name its columns.
+var yourlsColumns = []string{"keyword", "url", "title", "timestamp", "ip", "clicks"}
+
+// yourlsReader reads the rows of the YOURLS url table out of a MySQL dump,
+// ignoring every other statement. Timestamps are stored without a zone and
+// are taken as UTC.
+type yourlsReader struct {
+	sql      *sqlScanner
+	columns  []string
+	inValues bool
+	line     int
+}
+
+func (r *yourlsReader) Line() int {
+	return r.line
+}
+
+func (r *yourlsReader) Read() (entity.Link, error) {
+	for {
+		tok, err := r.sql.next()
+		if err != nil {
+			return entity.Link{}, err
+		}
+		if tok.kind == tokEOF {
+			return entity.Link{}, io.EOF
+		}
+
+		if r.inValues {
+			switch {
+			case tok.is("("):
+				return r.readRow(tok.line)
+			case tok.is(","):
+			default:
+				r.inValues = false
+			}
+			continue
+		}
+
+		if tok.isWord("INSERT") || tok.isWord("REPLACE") {
+			if err := r.readInsert(); err != nil {
+				return entity.Link{}, err
+			}
+		}
+	}
+}
+
+// readInsert reads an INSERT up to its VALUES keyword and starts reading
+// tuples if it targets the url table.
+func (r *yourlsReader) readInsert() error {
+	tok, err := r.sql.next()
+	for err == nil && tok.kind == tokWord && !tok.isWord("INTO") {
+		tok, err = r.sql.next()
+	}
+	if err != nil || !tok.isWord("INTO") {
+		return err
+	}
+
+	table, err := r.sql.next()
+	if err != nil {
+		return err
+	}
+	for {
+		dot, err := r.sql.peek()
+		if err != nil {
+			return err
+		}
+		if !dot.is(".") {
+			break
+		}
+		r.sql.next()
+		if table, err = r.sql.next(); err != nil {
+			return err
+		}
+	}
+
+	columns := yourlsColumns
+	tok, err = r.sql.next()
+	if err != nil {
+		return err
+	}
+	if tok.is("(") {
+		columns = nil
+		for {
+			if tok, err = r.sql.next(); err != nil {
+				return err
+			}
+			if tok.is(")") || tok.kind == tokEOF {
+				break
+			}
+			if !tok.is(",") {
+				columns = append(columns, strings.ToLower(tok.text))
+			}
+		}
+		if tok, err = r.sql.next(); err != nil {
+			return err
+		}
+	}
+
+	if (table.kind != tokWord && table.kind != tokIdent) || !strings.HasSuffix(strings.ToLower(table.text), "url") {
+		return nil
+	}
+	if tok.isWord("VALUES") || tok.isWord("VALUE") {
+		r.columns = columns
+		r.inValues = true
+	}
+	return nil
+}
+
+func (r *yourlsReader) readRow(line int) (entity.Link, error) {
+	r.line = line
+
+	var values []string
+	for {
+		tok, err := r.sql.next()
+		if err != nil {
+			return entity.Link{}, err
+		}
+		if tok.kind == tokEOF {
+			return entity.Link{}, fmt.Errorf("line %d: unterminated row", line)
+		}
+		if tok.is(")") {
+			break
+		}
+		if tok.is(",") {
+			continue
+		}
+		if tok.isWord("NULL") {
+			tok.text = ""
+		}
+		values = append(values, tok.text)
+	}
+
+	if len(values) != len(r.columns) {
+		return entity.Link{}, &RowError{Line: line, Err: fmt.Errorf("expected %d values, got %d", len(r.columns), len(values))}
+	}
+
+	row := make(map[string]string, len(values))
+	for i, column := range r.columns {
+		row[column] = values[i]
+	}
+
+	rec := record{
+		Code:        row["keyword"],
+		OriginalURL: row["url"],
+		Title:       row["title"],
+	}
+
+	fail := func(column string, err error) (entity.Link, error) {
+		return entity.Link{}, &RowError{Line: line, Code: rec.Code, Err: fmt.Errorf("%s: %w", column, err)}
+	}
+
+	if v := row["clicks"]; v != "" {
+		clicks, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fail("clicks", err)
+		}
+		rec.Clicks = clicks
+	}
+
+	if v := row["timestamp"]; v != "" && !strings.HasPrefix(v, "0000-00-00") {
+		created, err := time.Parse(time.DateTime, v)
+		if err != nil {
+			return fail("timestamp", err)
+		}
+		rec.CreatedAt = created
+	}
+
+	return rec.link(line)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokIdent
+	tokString
+	tokPunct
+)
+
+type sqlToken struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t sqlToken) is(punct string) bool {
+	return t.kind == tokPunct && t.text == punct
+}
+
+func (t sqlToken) isWord(word string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, word)
+}
+
+// sqlScanner splits a MySQL dump into tokens without holding more than one
+// in memory, so dumps of any size can be streamed.
+type sqlScanner struct {
+	r      *bufio.Reader
+	line   int
+	peeked *sqlToken
+}
+
+func newSQLScanner(r io.Reader) *sqlScanner {
+	return &sqlScanner{r: bufio.NewReaderSize(r, 64<<10), line: 1}
+}
+
+func (s *sqlScanner) peek() (sqlToken, error) {
+	if s.peeked == nil {
+		tok, err := s.scan()
+		if err != nil {
+			return sqlToken{}, err
+		}
+		s.peeked = &tok
+	}
+	return *s.peeked, nil
+}
+
+func (s *sqlScanner) next() (sqlToken, error) {
+	if s.peeked != nil {
+		tok := *s.peeked
+		s.peeked = nil
+		return tok, nil
+	}
+	return s.scan()
+}
+
+func (s *sqlScanner) scan() (sqlToken, error) {
+	for {
+		c, err := s.read()
+		if err == io.EOF {
+			return sqlToken{kind: tokEOF, line: s.line}, nil
+		}
+		if err != nil {
+			return sqlToken{}, err
+		}
+
+		line := s.line
+		switch {
+		case unicode.IsSpace(c):
+		case c == '#':
+			err = s.skipLine()
+		case c == '-' && s.follows('-'):
+			err = s.skipLine()
+		case c == '/' && s.follows('*'):
+			err = s.skipComment()
+		case c == '\'' || c == '"':
+			text, err := s.readQuoted(c)
+			return sqlToken{kind: tokString, text: text, line: line}, err
+		case c == '`':
+			text, err := s.readQuoted(c)
+			return sqlToken{kind: tokIdent, text: text, line: line}, err
+		case isWordRune(c) || (c == '-' && s.followsDigit()):
+			text, err := s.readWord(c)
+			return sqlToken{kind: tokWord, text: text, line: line}, err
+		default:
+			return sqlToken{kind: tokPunct, text: string(c), line: line}, nil
+		}
+		if err != nil {
+			return sqlToken{}, err
+		}
+	}
+}
+
+func (s *sqlScanner) read() (rune, error) {
+	c, _, err := s.r.ReadRune()
+	if c == '\n' {
+		s.line++
+	}
+	return c, err
+}
+
+func (s *sqlScanner) follows(want rune) bool {
+	c, _, err := s.r.ReadRune()
+	if err != nil {
+		return false
+	}
+	if c == want {
+		return true
+	}
+	s.r.UnreadRune()
+	return false
+}
+
+func (s *sqlScanner) followsDigit() bool {
+	c, _, err := s.r.ReadRune()
+	if err != nil {
+		return false
+	}
+	s.r.UnreadRune()
+	return unicode.IsDigit(c)
+}
+
+func (s *sqlScanner) skipLine() error {
+	for {
+		c, err := s.read()
+		if err == io.EOF || c == '\n' {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *sqlScanner) skipComment() error {
+	start := s.line
+	for {
+		c, err := s.read()
+		if err == io.EOF {
+			return fmt.Errorf("line %d: unterminated comment", start)
+		}
+		if err != nil {
+			return err
+		}
+		if c == '*' && s.follows('/') {
+			return nil
+		}
+	}
+}
+
+// readQuoted reads up to the closing quote, undoing doubled quotes and the
+// backslash escapes mysqldump writes.
+func (s *sqlScanner) readQuoted(quote rune) (string, error) {
+	start := s.line
+	var b strings.Builder
+	for {
+		c, err := s.read()
+		if err == io.EOF {
+			return "", fmt.Errorf("line %d: unterminated string", start)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case c == quote && s.follows(quote):
+			b.WriteRune(quote)
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote != '`':
+			c, err = s.read()
+			if err == io.EOF {
+				return "", fmt.Errorf("line %d: unterminated string", start)
+			}
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(unescape(c))
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+func (s *sqlScanner) readWord(first rune) (string, error) {
+	var b strings.Builder
+	b.WriteRune(first)
+	for {
+		c, _, err := s.r.ReadRune()
+		if err == io.EOF {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !isWordRune(c) {
+			s.r.UnreadRune()
+			return b.String(), nil
+		}
+		b.WriteRune(c)
+	}
+}
+
+func isWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '$'
+}
+
+func unescape(c rune) rune {
+	switch c {
+	case '0':
+		return 0
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'Z':
+		return 0x1a
+	default:
+		return c
+	}
+}
@@ -0,0 +1,223 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/entity"
+)
+
+func TestSQLScanner(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "words and punctuation",
+			sql:  "INSERT INTO t VALUES (1,-2);",
+			want: []string{"w:INSERT", "w:INTO", "w:t", "w:VALUES", "p:(", "w:1", "p:,", "w:-2", "p:)", "p:;"},
+		},
+		{
+			name: "quoted strings",
+			sql:  `'it''s' "say ""hi""" ` + "`my``table`",
+			want: []string{"s:it's", `s:say "hi"`, "i:my`table"},
+		},
+		{
+			name: "backslash escapes",
+			sql:  `'a\'b' 'c\\d' 'line\nbreak\ttab' 'q\"x'`,
+			want: []string{"s:a'b", `s:c\d`, "s:line\nbreak\ttab", `s:q"x`},
+		},
+		{
+			name: "backslashes in identifiers are literal",
+			sql:  "`a\\b`",
+			want: []string{`i:a\b`},
+		},
+		{
+			name: "comments",
+			sql:  "-- dump header; INSERT\n# mysql comment\n/* block\n(INSERT) */ SELECT /*!40101 x */ 1 -- trailing",
+			want: []string{"w:SELECT", "w:1"},
+		},
+		{
+			name: "comment markers inside strings",
+			sql:  `'-- not a comment' '/* nor this */'`,
+			want: []string{"s:-- not a comment", "s:/* nor this */"},
+		},
+		{
+			name: "a lone minus",
+			sql:  "a - b",
+			want: []string{"w:a", "p:-", "w:b"},
+		},
+	}
+
+	kinds := map[tokenKind]string{tokWord: "w", tokIdent: "i", tokString: "s", tokPunct: "p"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSQLScanner(strings.NewReader(tt.sql))
+			var got []string
+			for {
+				tok, err := s.next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tok.kind == tokEOF {
+					break
+				}
+				got = append(got, kinds[tok.kind]+":"+tok.text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLScannerErrors(t *testing.T) {
+	for _, sql := range []string{"'open", `'escaped\'`, "`open", "/* open"} {
+		s := newSQLScanner(strings.NewReader(sql))
+		if _, err := s.next(); err == nil {
+			t.Errorf("next() on %q succeeded, want an error", sql)
+		}
+	}
+}
+
+func TestSQLScannerLines(t *testing.T) {
+	s := newSQLScanner(strings.NewReader("a\n/* b\n */ 'c\nd'\ne"))
+	var lines []int
+	for {
+		tok, err := s.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.kind == tokEOF {
+			break
+		}
+		lines = append(lines, tok.line)
+	}
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("token lines %v, want %v", lines, want)
+	}
+}
+
+// readAll returns the links read from r and the lines of its invalid rows.
+func readAll(t *testing.T, r LinkReader) ([]entity.Link, []int) {
+	t.Helper()
+	var links []entity.Link
+	var invalid []int
+	for {
+		link, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return links, invalid
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			invalid = append(invalid, rowErr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, link)
+	}
+}
+
+func TestYOURLSReader(t *testing.T) {
+	created := time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		dump        string
+		want        []entity.Link
+		wantInvalid []int
+	}{
+		{
+			name: "multi-row insert in table order",
+			dump: "INSERT INTO `yourls_url` VALUES ('gh','https://github.com','GitHub, \\'the\\' site','2019-03-04 05:06:07','127.0.0.1',12)," +
+				"('ex','https://example.com',NULL,'2019-03-04 05:06:07','::1',0);",
+			want: []entity.Link{
+				{Code: "gh", OriginalURL: "https://github.com", Title: "GitHub, 'the' site", CreatedAt: created, Clicks: 12},
+				{Code: "ex", OriginalURL: "https://example.com", CreatedAt: created},
+			},
+		},
+		{
+			name: "named columns in any order",
+			dump: "INSERT IGNORE INTO `shorty`.`yourls_url` (`clicks`, `url`, `keyword`) VALUES (3, 'https://example.com', 'ex');",
+			want: []entity.Link{{Code: "ex", OriginalURL: "https://example.com", Clicks: 3}},
+		},
+		{
+			name: "other tables are ignored",
+			dump: "INSERT INTO `yourls_log` VALUES (1,'2019-03-04 05:06:07','gh','https://ref.example','Mozilla','127.0.0.1','US');\n" +
+				"INSERT INTO `yourls_options` VALUES (1,'version','1.9');\n" +
+				"REPLACE INTO yourls_url VALUES ('gh','https://github.com','','0000-00-00 00:00:00','127.0.0.1',1);",
+			want: []entity.Link{{Code: "gh", OriginalURL: "https://github.com", Clicks: 1}},
+		},
+		{
+			name: "whole mysqldump",
+			dump: "-- MySQL dump 10.13\n" +
+				"/*!40101 SET NAMES utf8mb4 */;\n" +
+				"DROP TABLE IF EXISTS `yourls_url`;\n" +
+				"CREATE TABLE `yourls_url` (\n  `keyword` varchar(100) NOT NULL,\n  `url` text NOT NULL,\n  PRIMARY KEY (`keyword`)\n);\n" +
+				"LOCK TABLES `yourls_url` WRITE;\n" +
+				"/*!40000 ALTER TABLE `yourls_url` DISABLE KEYS */;\n" +
+				"INSERT INTO `yourls_url` VALUES ('a1','https://a.example','A; (not) the end','2019-03-04 05:06:07','127.0.0.1',5);\n" +
+				"UNLOCK TABLES;\n",
+			want: []entity.Link{{Code: "a1", OriginalURL: "https://a.example", Title: "A; (not) the end", CreatedAt: created, Clicks: 5}},
+		},
+		{
+			name: "invalid rows are reported by line",
+			dump: "INSERT INTO yourls_url VALUES\n" +
+				"('ok','https://ok.example','','2019-03-04 05:06:07','',1),\n" +
+				"('short','https://short.example'),\n" +
+				"('bad url','ftp://files.example','','2019-03-04 05:06:07','',1),\n" +
+				"('clicks','https://c.example','','2019-03-04 05:06:07','',many),\n" +
+				"('last','https://last.example','','2019-03-04 05:06:07','',2);",
+			want: []entity.Link{
+				{Code: "ok", OriginalURL: "https://ok.example", CreatedAt: created, Clicks: 1},
+				{Code: "last", OriginalURL: "https://last.example", CreatedAt: created, Clicks: 2},
+			},
+			wantInvalid: []int{3, 4, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, invalid := readAll(t, NewReader(strings.NewReader(tt.dump), YOURLS))
+			if !reflect.DeepEqual(links, tt.want) {
+				t.Errorf("links\n got %+v\nwant %+v", links, tt.want)
+			}
+			if !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Errorf("invalid lines %v, want %v", invalid, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestImportYOURLSReport(t *testing.T) {
+	dump := "INSERT INTO yourls_url VALUES\n" +
+		"('new','https://new.example','','2019-03-04 05:06:07','',1),\n" +
+		"('taken','https://taken.example','','2019-03-04 05:06:07','',2),\n" +
+		"('x','https://x.example','','nope','',3);"
+	dst := target{"taken": {Code: "taken", OriginalURL: "https://old.example"}}
+
+	report, err := Import(context.Background(), dst, NewReader(strings.NewReader(dump), YOURLS), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Report{
+		Imported: 1,
+		Skipped:  []Issue{{Line: 3, Code: "taken", Reason: "code already exists"}},
+		Invalid:  []Issue{{Line: 4, Code: "x", Reason: `timestamp: parsing time "nope" as "2006-01-02 15:04:05": cannot parse "nope" as "2006"`}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report\n got %+v\nwant %+v", report, want)
+	}
+	if dst["taken"].OriginalURL != "https://old.example" {
+		t.Errorf("skipped code was changed to %s", dst["taken"].OriginalURL)
+	}
+}
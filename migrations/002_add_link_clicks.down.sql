@@ -0,0 +1,3 @@
+ALTER TABLE links
+    DROP COLUMN IF EXISTS last_clicked_at,
+    DROP COLUMN IF EXISTS clicks;
@@ -0,0 +1,3 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_clicked_at TIMESTAMP NULL;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS api_keys;
+
+DROP INDEX IF EXISTS idx_links_expires_at;
+DROP INDEX IF EXISTS idx_links_owner;
+
+ALTER TABLE links
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS owner;
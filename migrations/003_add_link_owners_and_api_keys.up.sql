@@ -0,0 +1,17 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS owner VARCHAR(100) NULL,
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP NULL,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS idx_links_owner ON links(owner);
+CREATE INDEX IF NOT EXISTS idx_links_expires_at ON links(expires_at) WHERE expires_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    owner VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP NULL
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_links_metadata_fetched_at;
+
+ALTER TABLE links
+    DROP COLUMN IF EXISTS metadata_fetched_at,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS favicon_url,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,7 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS description TEXT NULL,
+    ADD COLUMN IF NOT EXISTS favicon_url TEXT NULL,
+    ADD COLUMN IF NOT EXISTS image_url TEXT NULL,
+    ADD COLUMN IF NOT EXISTS metadata_fetched_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS idx_links_metadata_fetched_at ON links(metadata_fetched_at NULLS FIRST);
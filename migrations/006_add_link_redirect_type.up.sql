@@ -0,0 +1,2 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS redirect_type SMALLINT NULL;
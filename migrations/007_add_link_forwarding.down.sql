@@ -0,0 +1,3 @@
+ALTER TABLE links
+    DROP COLUMN IF EXISTS forward_query,
+    DROP COLUMN IF EXISTS forward_path;
@@ -0,0 +1,3 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS forward_path BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS forward_query VARCHAR(20) NULL;
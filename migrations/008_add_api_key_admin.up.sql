@@ -0,0 +1,2 @@
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS admin BOOLEAN NOT NULL DEFAULT FALSE;
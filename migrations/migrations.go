@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS holds every SQL migration so the binaries do not depend on the working
+// directory they are started from.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var errNoLocation = errors.New("client: redirect response has no Location header")
+
+type ShortenRequest struct {
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// RedirectType is 301, 302, 307 or 308; zero uses the server default.
+	RedirectType int `json:"redirect_type,omitempty"`
+	// ForwardPath appends the path visited after the code to the URL.
+	ForwardPath bool `json:"forward_path,omitempty"`
+	// ForwardQuery merges the visited query into the URL's own:
+	// "prefer_request" lets visited values win on shared keys, "prefer_link"
+	// keeps the URL's. Empty drops the visited query.
+	ForwardQuery string `json:"forward_query,omitempty"`
+}
+
+type Link struct {
+	Code          string     `json:"code"`
+	ShortURL      string     `json:"short_url"`
+	QRURL         string     `json:"qr_url"`
+	OriginalURL   string     `json:"original_url"`
+	Title         string     `json:"title,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	FaviconURL    string     `json:"favicon_url,omitempty"`
+	ImageURL      string     `json:"image_url,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Clicks        int64      `json:"clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+	Owner         string     `json:"owner,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
+	RedirectType  int        `json:"redirect_type"`
+	ForwardPath   bool       `json:"forward_path,omitempty"`
+	ForwardQuery  string     `json:"forward_query,omitempty"`
+}
+
+// BatchResult is the outcome of one item of ShortenBatch. Err is an
+// *APIError built from the item's status when it failed, or ErrMissingResult
+// when the response left the item out. Exactly one of Link and Err is set.
+type BatchResult struct {
+	Link *Link
+	Err  error
+}
+
+type Stats struct {
+	Code          string     `json:"code"`
+	Clicks        int64      `json:"clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ListOptions pages through links, newest first. Zero values use the
+// server defaults.
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+type listResponse struct {
+	Links []Link `json:"links"`
+}
+
+func (c *Client) Shorten(ctx context.Context, req ShortenRequest) (Link, error) {
+	resp, err := c.do(ctx, c.httpClient, http.MethodPost, "/api/v1/links", nil, req)
+	if err != nil {
+		return Link{}, err
+	}
+
+	var link Link
+	err = decode(resp, &link)
+	return link, err
+}
+
+// ShortenBatch shortens up to the server's batch limit of URLs in one
+// request and returns a result per item, in order. The returned error is set
+// only when the request as a whole failed.
+func (c *Client) ShortenBatch(ctx context.Context, reqs []ShortenRequest) ([]BatchResult, error) {
+	resp, err := c.do(ctx, c.httpClient, http.MethodPost, "/api/v1/links/batch", nil, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Results []struct {
+			Index  int    `json:"index"`
+			Status int    `json:"status"`
+			Link   *Link  `json:"link"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	if err := decode(resp, &body); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(reqs))
+	for _, item := range body.Results {
+		if item.Index < 0 || item.Index >= len(results) {
+			continue
+		}
+		if item.Link != nil {
+			results[item.Index].Link = item.Link
+		} else {
+			results[item.Index].Err = &APIError{StatusCode: item.Status, Message: item.Error}
+		}
+	}
+	for i := range results {
+		if results[i].Link == nil && results[i].Err == nil {
+			results[i].Err = ErrMissingResult
+		}
+	}
+	return results, nil
+}
+
+// Resolve returns the destination of code without following the redirect,
+// so the destination itself is never requested. The server still counts it
+// as a click on the link, like any other visit.
+func (c *Client) Resolve(ctx context.Context, code string) (string, error) {
+	resp, err := c.do(ctx, c.noRedirect, http.MethodGet, "/"+url.PathEscape(code), nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer discard(resp)
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", errNoLocation
+	}
+	return location, nil
+}
+
+func (c *Client) Get(ctx context.Context, code string) (Link, error) {
+	resp, err := c.do(ctx, c.httpClient, http.MethodGet, "/api/v1/links/"+url.PathEscape(code), nil, nil)
+	if err != nil {
+		return Link{}, err
+	}
+
+	var link Link
+	err = decode(resp, &link)
+	return link, err
+}
+
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]Link, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	resp, err := c.do(ctx, c.httpClient, http.MethodGet, "/api/v1/links", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list listResponse
+	err = decode(resp, &list)
+	return list.Links, err
+}
+
+func (c *Client) Delete(ctx context.Context, code string) error {
+	resp, err := c.do(ctx, c.httpClient, http.MethodDelete, "/api/v1/links/"+url.PathEscape(code), nil, nil)
+	if err != nil {
+		return err
+	}
+	discard(resp)
+	return nil
+}
+
+func (c *Client) Stats(ctx context.Context, code string) (Stats, error) {
+	resp, err := c.do(ctx, c.httpClient, http.MethodGet, "/api/v1/links/"+url.PathEscape(code)+"/stats", nil, nil)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	err = decode(resp, &stats)
+	return stats, err
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShortenBatchResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr []error
+	}{
+		{
+			name: "every item answered",
+			body: `{"results":[{"index":0,"status":201,"link":{"code":"aaa"}},{"index":1,"status":409,"error":"alias is already in use"}]}`,
+			want: []string{"aaa", ""}, wantErr: []error{nil, ErrConflict},
+		},
+		{
+			name: "out of order",
+			body: `{"results":[{"index":1,"status":201,"link":{"code":"bbb"}},{"index":0,"status":201,"link":{"code":"aaa"}}]}`,
+			want: []string{"aaa", "bbb"}, wantErr: []error{nil, nil},
+		},
+		{
+			name: "missing item",
+			body: `{"results":[{"index":1,"status":201,"link":{"code":"bbb"}}]}`,
+			want: []string{"", "bbb"}, wantErr: []error{ErrMissingResult, nil},
+		},
+		{
+			name: "unknown indices are ignored",
+			body: `{"results":[{"index":-1,"status":201,"link":{"code":"xxx"}},{"index":7,"status":201,"link":{"code":"yyy"}}]}`,
+			want: []string{"", ""}, wantErr: []error{ErrMissingResult, ErrMissingResult},
+		},
+		{
+			name: "no results",
+			body: `{}`,
+			want: []string{"", ""}, wantErr: []error{ErrMissingResult, ErrMissingResult},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := c.ShortenBatch(context.Background(), []ShortenRequest{{URL: "https://a.example"}, {URL: "https://b.example"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, result := range results {
+				if (result.Link == nil) == (result.Err == nil) {
+					t.Errorf("item %d: Link %v and Err %v, want exactly one", i, result.Link, result.Err)
+				}
+				if !errors.Is(result.Err, tt.wantErr[i]) {
+					t.Errorf("item %d: Err %v, want %v", i, result.Err, tt.wantErr[i])
+				}
+				if result.Link != nil && result.Link.Code != tt.want[i] {
+					t.Errorf("item %d: code %q, want %q", i, result.Link.Code, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package shorty embeds the go-shorty shortener in another Go program, either
+// called directly or mounted as an http.Handler on the host's own mux.
+package shorty
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Igorjr19/go-shorty/internal/api"
+	"github.com/Igorjr19/go-shorty/internal/auth"
+	"github.com/Igorjr19/go-shorty/internal/entity"
+	"github.com/Igorjr19/go-shorty/internal/shortener"
+	"github.com/Igorjr19/go-shorty/internal/storage"
+)
+
+type (
+	Link          = entity.Link
+	Request       = shortener.Request
+	Policy        = shortener.Policy
+	CodeGenerator = shortener.CodeGenerator
+	Validator     = shortener.Validator
+	BatchResult   = shortener.BatchResult
+	ListOptions   = storage.ListOptions
+)
+
+// Storage is where links are kept. NewMemoryStorage and NewPostgresStorage
+// return the built-in backends, but any implementation will do.
+type Storage interface {
+	// Save fails with ErrConflict when the code is taken.
+	Save(ctx context.Context, link Link) error
+	// SaveBatch returns an error per link: nil when saved, ErrConflict when
+	// its code was taken. The second error reports a failure of the batch.
+	SaveBatch(ctx context.Context, links []Link) ([]error, error)
+	// Load fails with ErrNotFound for unknown codes.
+	Load(ctx context.Context, code string) (Link, error)
+	List(ctx context.Context, opts ListOptions) ([]Link, error)
+	Delete(ctx context.Context, code string) error
+	RecordClick(ctx context.Context, code string, at time.Time) error
+}
+
+// Identity is who a request to the HTTP handler is made by. Links created by
+// an identity record its owner, and it can only manage those unless Admin is
+// set.
+type Identity struct {
+	Owner string
+	Admin bool
+}
+
+// Authenticator identifies the caller of a request to the HTTP handler. It
+// returns false for anonymous requests, which may only follow and create
+// links. An identity with neither an owner nor Admin counts as anonymous.
+type Authenticator func(r *http.Request) (Identity, bool)
+
+var (
+	ErrNotFound      = storage.ErrNotFound
+	ErrConflict      = storage.ErrConflict
+	ErrInvalidURL    = shortener.ErrInvalidURL
+	ErrBlockedDomain = shortener.ErrBlockedDomain
+	ErrInvalidAlias  = shortener.ErrInvalidAlias
+	ErrReservedAlias = shortener.ErrReservedAlias
+	ErrAliasTaken    = shortener.ErrAliasTaken
+	ErrRejected      = shortener.ErrRejected
+	ErrInvalidExpiry = shortener.ErrInvalidExpiry
+	ErrLinkDisabled  = shortener.ErrLinkDisabled
+	ErrLinkExpired   = shortener.ErrLinkExpired
+
+	ErrInvalidRedirectType    = shortener.ErrInvalidRedirectType
+	ErrInvalidQueryForwarding = shortener.ErrInvalidQueryForwarding
+)
+
+// RandomCode is the CodeGenerator used when none is configured.
+var RandomCode CodeGenerator = shortener.RandomCode
+
+func NewMemoryStorage() Storage {
+	return storage.NewMemoryStorage()
+}
+
+// NewPostgresStorage stores links in db, which must already have the
+// migrations in the migrations package applied.
+func NewPostgresStorage(db *sql.DB) Storage {
+	return storage.NewPostgresStorage(db)
+}
+
+type Option func(*config)
+
+type config struct {
+	storage      Storage
+	service      shortener.Options
+	baseURL      string
+	redirectType int
+	authenticate Authenticator
+}
+
+// WithStorage sets the backend. Links are kept in memory by default.
+func WithStorage(s Storage) Option {
+	return func(c *config) {
+		c.storage = s
+	}
+}
+
+func WithCodeGenerator(g CodeGenerator) Option {
+	return func(c *config) {
+		c.service.Generator = g
+	}
+}
+
+func WithCodeLength(n int) Option {
+	return func(c *config) {
+		c.service.CodeLength = n
+	}
+}
+
+func WithValidator(v Validator) Option {
+	return func(c *config) {
+		c.service.Validator = v
+	}
+}
+
+// WithClock replaces time.Now for creation and click timestamps.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.service.Clock = now
+	}
+}
+
+func WithPolicy(p Policy) Option {
+	return func(c *config) {
+		c.service.Policy = p
+	}
+}
+
+// WithTimeouts bounds every storage read and write. Zero means no limit
+// beyond the caller's context.
+func WithTimeouts(read, write time.Duration) Option {
+	return func(c *config) {
+		c.service.ReadTimeout = read
+		c.service.WriteTimeout = write
+	}
+}
+
+// WithBaseURL sets the public address used for short URLs returned by the
+// HTTP handler. Without it they are derived from each request.
+func WithBaseURL(u string) Option {
+	return func(c *config) {
+		c.baseURL = u
+	}
+}
+
+// WithRedirectType sets the status the HTTP handler redirects with for links
+// created without one: 301, 302 (the default), 307 or 308.
+func WithRedirectType(status int) Option {
+	return func(c *config) {
+		c.redirectType = status
+	}
+}
+
+// WithAuth lets the HTTP handler serve the routes that list, show, delete,
+// import and export links, to the callers authenticate identifies. Without it
+// the handler only follows and creates links.
+func WithAuth(authenticate Authenticator) Option {
+	return func(c *config) {
+		c.authenticate = authenticate
+	}
+}
+
+type Shortener struct {
+	service      *shortener.Service
+	baseURL      string
+	redirectType int
+	authenticate Authenticator
+}
+
+func New(opts ...Option) *Shortener {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.storage == nil {
+		c.storage = storage.NewMemoryStorage()
+	}
+
+	return &Shortener{
+		service:      shortener.NewService(backend(c.storage), c.service),
+		baseURL:      c.baseURL,
+		redirectType: c.redirectType,
+		authenticate: c.authenticate,
+	}
+}
+
+// backend returns the built-in storages as they are and completes others
+// with the operations only imports and exports use.
+func backend(s Storage) storage.Storage {
+	if full, ok := s.(storage.Storage); ok {
+		return full
+	}
+	return customStorage{s}
+}
+
+type customStorage struct {
+	Storage
+}
+
+func (c customStorage) Each(ctx context.Context, opts storage.ListOptions, fn func(entity.Link) error) error {
+	const pageSize = 500
+
+	limit := opts.Limit
+	for {
+		page := opts
+		page.Limit = pageSize
+		if limit > 0 && limit < pageSize {
+			page.Limit = limit
+		}
+
+		links, err := c.List(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err := fn(link); err != nil {
+				return err
+			}
+		}
+
+		if limit > 0 {
+			limit -= len(links)
+			if limit <= 0 {
+				return nil
+			}
+		}
+		if len(links) < page.Limit {
+			return nil
+		}
+		opts.Offset += len(links)
+	}
+}
+
+func (customStorage) Restore(ctx context.Context, link entity.Link, overwrite bool) error {
+	return errors.ErrUnsupported
+}
+
+func (s *Shortener) Shorten(ctx context.Context, req Request) (Link, error) {
+	return s.service.Shorten(ctx, req)
+}
+
+// ShortenBatch saves the valid requests together and returns one result per
+// request, in order.
+func (s *Shortener) ShortenBatch(ctx context.Context, reqs []Request) ([]BatchResult, error) {
+	return s.service.ShortenBatch(ctx, reqs)
+}
+
+// Resolve returns the destination for code and counts the visit.
+func (s *Shortener) Resolve(ctx context.Context, code string) (string, error) {
+	return s.service.Resolve(ctx, code)
+}
+
+// Preview returns the link Resolve would follow, without counting a visit.
+func (s *Shortener) Preview(ctx context.Context, code string) (Link, error) {
+	return s.service.Preview(ctx, code)
+}
+
+func (s *Shortener) Get(ctx context.Context, code string) (Link, error) {
+	return s.service.Get(ctx, code)
+}
+
+func (s *Shortener) List(ctx context.Context, opts ListOptions) ([]Link, error) {
+	return s.service.List(ctx, opts)
+}
+
+func (s *Shortener) Delete(ctx context.Context, code string) error {
+	return s.service.Delete(ctx, code)
+}
+
+// SetPolicy swaps the blocklist and reserved aliases while running.
+func (s *Shortener) SetPolicy(p Policy) {
+	s.service.SetPolicy(p)
+}
+
+// Handler serves the same routes as the go-shorty server below prefix:
+//
+//	mux.Handle("/go/", s.Handler("/go"))
+//
+// Only redirects and shortening are served unless WithAuth is set. No rate
+// limiting is applied; wrap the handler to add it.
+func (s *Shortener) Handler(prefix string) http.Handler {
+	prefix = strings.TrimRight(prefix, "/")
+
+	opts := api.Options{
+		BaseURL:      s.baseURL,
+		PathPrefix:   prefix,
+		RedirectType: s.redirectType,
+	}
+	if s.authenticate != nil {
+		opts.Auth = s.authMiddleware
+	}
+
+	handler := api.NewHandler(s.service, opts)
+
+	mux := http.NewServeMux()
+	handler.Register(mux, nil, nil)
+
+	if prefix == "" {
+		return mux
+	}
+	return http.StripPrefix(prefix, mux)
+}
+
+func (s *Shortener) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := s.authenticate(r)
+		if !ok || (id.Owner == "" && !id.Admin) {
+			next(w, r)
+			return
+		}
+		key := entity.APIKey{Owner: id.Owner, Admin: id.Admin}
+		next(w, r.WithContext(auth.WithKey(r.Context(), key)))
+	}
+}